@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"github.com/geekflow/straw/internal/agent"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// interruptContext returns a context that is canceled on SIGINT or SIGTERM.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			log.Printf("Signal(%d) is captured", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+
+	return ctx, cancel
+}
+
+// runRecord implements "straw record", writing the metrics gathered by the
+// configured inputs to a file.
+func runRecord(args []string) error {
+	flags := flag.NewFlagSet("record", flag.ExitOnError)
+	duration := flags.Duration("duration", 0, "how long to record, 0 records until interrupted")
+	out := flags.String("out", "", "file to write the captured metrics to, - for stdout")
+	flags.Parse(args)
+
+	if *out == "" {
+		return errors.New("record: missing --out file")
+	}
+
+	c, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	ag, err := agent.NewAgent(c)
+	if err != nil {
+		return err
+	}
+
+	w := os.Stdout
+	if *out != "-" {
+		w, err = os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+	if *duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	log.Printf("Recording to %s", *out)
+	return ag.Record(ctx, w)
+}
+
+// runReplay implements "straw replay", writing the metrics of a captured
+// file to the configured outputs.
+func runReplay(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	preserveTiming := flags.Bool("preserve-timing", false,
+		"send metrics with the spacing of their timestamps instead of at full speed")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("replay: expected one capture file")
+	}
+
+	c, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if len(c.Outputs) == 0 && len(c.Pipelines) == 0 {
+		return errors.New("Error: no outputs found, did you provide a valid config file?")
+	}
+
+	ag, err := agent.NewAgent(c)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	start := time.Now()
+	err = ag.Replay(ctx, f, *preserveTiming)
+	log.Printf("Replay of %s finished in %s", flags.Arg(0), time.Since(start))
+	return err
+}
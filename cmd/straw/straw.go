@@ -16,8 +16,10 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	_ "github.com/geekflow/straw/plugins/aggregators/all"
 	_ "github.com/geekflow/straw/plugins/inputs/all"
 	_ "github.com/geekflow/straw/plugins/outputs/all"
+	_ "github.com/geekflow/straw/plugins/processors/all"
 )
 
 const projectName string = "Straw"
@@ -104,36 +106,51 @@ func signalProcess() {
 	}
 }
 
-func runAgent(ctx context.Context) error {
-	log.Printf("Starting %s %s", projectName, version)
-
+// loadConfig loads and validates the configuration files.
+func loadConfig() (*config.Config, error) {
 	c := config.NewConfig()
 	err := c.LoadConfig(*fConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if *fConfigDirectory != "" {
 		err = c.LoadDirectory(*fConfigDirectory)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	if len(c.Outputs) == 0 {
-		return errors.New("Error: no outputs found, did you provide a valid config file?")
-	}
-
 	if int64(c.Agent.Interval.Duration) <= 0 {
-		return fmt.Errorf("Agent interval must be positive, found %s",
+		return nil, fmt.Errorf("Agent interval must be positive, found %s",
 			c.Agent.Interval.Duration)
 	}
 
 	if int64(c.Agent.FlushInterval.Duration) <= 0 {
-		return fmt.Errorf("Agent flush_interval must be positive; found %s",
+		return nil, fmt.Errorf("Agent flush_interval must be positive; found %s",
 			c.Agent.Interval.Duration)
 	}
 
+	if c.Agent.MetricChannelSize <= 0 {
+		return nil, fmt.Errorf("Agent metric_channel_size must be positive; found %d",
+			c.Agent.MetricChannelSize)
+	}
+
+	return c, nil
+}
+
+func runAgent(ctx context.Context) error {
+	log.Printf("Starting %s %s", projectName, version)
+
+	c, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if len(c.Outputs) == 0 && len(c.Pipelines) == 0 {
+		return errors.New("Error: no outputs found, did you provide a valid config file?")
+	}
+
 	ag, err := agent.NewAgent(c)
 	if err != nil {
 		return err
@@ -155,6 +172,10 @@ func runAgent(ctx context.Context) error {
 	log.Printf("Loaded inputs: %s", strings.Join(c.InputNames(), " "))
 	log.Printf("Loaded outputs: %s", strings.Join(c.OutputNames(), " "))
 	log.Printf("Tags enabled: %s", c.ListTags())
+	for _, p := range c.Pipelines {
+		log.Printf("Loaded pipeline %s: inputs: %s outputs: %s", p.Name,
+			strings.Join(p.InputNames(), " "), strings.Join(p.OutputNames(), " "))
+	}
 
 	if *fPidFile != "" {
 		f, err := os.OpenFile(*fPidFile, os.O_CREATE|os.O_WRONLY, 0644)
@@ -197,5 +218,21 @@ func main() {
 		log.Println(projectName + " version already configured to: " + internal.Version())
 	}
 
+	if args := flag.Args(); len(args) > 0 {
+		var err error
+		switch args[0] {
+		case "record":
+			err = runRecord(args[1:])
+		case "replay":
+			err = runReplay(args[1:])
+		default:
+			usageExit(1)
+		}
+		if err != nil {
+			log.Fatalf("[%s] %v", projectName, err)
+		}
+		return
+	}
+
 	run()
 }
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/internal/models"
 	"github.com/geekflow/straw/metric"
 	"github.com/geekflow/straw/plugins"
 	"time"
@@ -12,22 +13,34 @@ import (
 type MetricMaker interface {
 	LogName() string
 	MakeMetric(metric internal.Metric) internal.Metric
+	// Process applies the processors of the maker to a made metric.
+	Process(metric internal.Metric) []internal.Metric
+	OverflowPolicy() models.OverflowPolicy
+	MetricDropped(metric internal.Metric)
 }
 
 type accumulator struct {
 	maker     MetricMaker
-	metrics   chan<- internal.Metric
+	metrics   chan internal.Metric
+	policy    models.OverflowPolicy
 	precision time.Duration
+	clock     internal.Clock
 }
 
+// NewAccumulator returns an Accumulator sending the metrics made by maker to
+// the metrics channel.  Metrics without a timestamp are stamped with the time
+// of the clock.
 func NewAccumulator(
 	maker MetricMaker,
-	metrics chan<- internal.Metric,
+	metrics chan internal.Metric,
+	clock internal.Clock,
 ) plugins.Accumulator {
 	acc := accumulator{
 		maker:     maker,
 		metrics:   metrics,
+		policy:    maker.OverflowPolicy(),
 		precision: time.Nanosecond,
+		clock:     clock,
 	}
 	return &acc
 }
@@ -71,6 +84,38 @@ func (ac *accumulator) addFields(
 		return
 	}
 	if m := ac.maker.MakeMetric(m); m != nil {
+		for _, m := range ac.maker.Process(m) {
+			ac.send(m)
+		}
+	}
+}
+
+// send adds the metric to the channel, applying the overflow policy if the
+// channel is full.  With drop_oldest the channel must only hold metrics made
+// by the maker, see runInputs.
+func (ac *accumulator) send(m internal.Metric) {
+	switch ac.policy {
+	case models.OverflowDropNewest:
+		select {
+		case ac.metrics <- m:
+		default:
+			ac.maker.MetricDropped(m)
+		}
+	case models.OverflowDropOldest:
+		for {
+			select {
+			case ac.metrics <- m:
+				return
+			default:
+			}
+
+			select {
+			case old := <-ac.metrics:
+				ac.maker.MetricDropped(old)
+			default:
+			}
+		}
+	default:
 		ac.metrics <- m
 	}
 }
@@ -93,7 +138,7 @@ func (ac *accumulator) getTime(t []time.Time) time.Time {
 	if len(t) > 0 {
 		timestamp = t[0]
 	} else {
-		timestamp = time.Now()
+		timestamp = ac.clock.Now()
 	}
 	return timestamp.Round(ac.precision)
 }
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+type testMaker struct {
+	policy  models.OverflowPolicy
+	dropped []internal.Metric
+}
+
+func (*testMaker) LogName() string { return "inputs.test" }
+
+func (*testMaker) MakeMetric(m internal.Metric) internal.Metric { return m }
+
+func (*testMaker) Process(m internal.Metric) []internal.Metric { return []internal.Metric{m} }
+
+func (tm *testMaker) OverflowPolicy() models.OverflowPolicy { return tm.policy }
+
+func (tm *testMaker) MetricDropped(m internal.Metric) {
+	tm.dropped = append(tm.dropped, m)
+}
+
+func TestAccumulatorOverflowDropNewest(t *testing.T) {
+	metrics := make(chan internal.Metric, 1)
+	maker := &testMaker{policy: models.OverflowDropNewest}
+	acc := NewAccumulator(maker, metrics, internal.RealClock)
+
+	now := time.Now()
+	acc.AddFields("first", map[string]interface{}{"value": 1}, nil, now)
+	acc.AddFields("second", map[string]interface{}{"value": 2}, nil, now)
+
+	require.Len(t, maker.dropped, 1)
+	require.Equal(t, "second", maker.dropped[0].Name())
+	require.Equal(t, "first", (<-metrics).Name())
+}
+
+func TestAccumulatorOverflowDropOldest(t *testing.T) {
+	metrics := make(chan internal.Metric, 1)
+	maker := &testMaker{policy: models.OverflowDropOldest}
+	acc := NewAccumulator(maker, metrics, internal.RealClock)
+
+	now := time.Now()
+	acc.AddFields("first", map[string]interface{}{"value": 1}, nil, now)
+	acc.AddFields("second", map[string]interface{}{"value": 2}, nil, now)
+
+	require.Len(t, maker.dropped, 1)
+	require.Equal(t, "first", maker.dropped[0].Name())
+	require.Equal(t, "second", (<-metrics).Name())
+}
+
+func TestAccumulatorOverflowBlock(t *testing.T) {
+	metrics := make(chan internal.Metric, 1)
+	maker := &testMaker{policy: models.OverflowBlock}
+	acc := NewAccumulator(maker, metrics, internal.RealClock)
+
+	now := time.Now()
+	acc.AddFields("first", map[string]interface{}{"value": 1}, nil, now)
+
+	done := make(chan struct{})
+	go func() {
+		acc.AddFields("second", map[string]interface{}{"value": 2}, nil, now)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("AddFields did not block on a full channel")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.Equal(t, "first", (<-metrics).Name())
+	<-done
+	require.Equal(t, "second", (<-metrics).Name())
+	require.Empty(t, maker.dropped)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/geekflow/straw/internal"
 	"github.com/geekflow/straw/internal/config"
 	"github.com/geekflow/straw/internal/models"
+	"github.com/geekflow/straw/internal/schedule"
 	"github.com/geekflow/straw/plugins"
 	"runtime"
 	"sync"
@@ -17,12 +18,16 @@ import (
 // Agent runs a set of plugins.
 type Agent struct {
 	Config *config.Config
+
+	// Clock is used for scheduling and for timestamping metrics.
+	Clock internal.Clock
 }
 
 // NewAgent returns an Agent for the given Config.
 func NewAgent(config *config.Config) (*Agent, error) {
 	a := &Agent{
 		Config: config,
+		Clock:  internal.RealClock,
 	}
 	return a, nil
 }
@@ -39,132 +44,247 @@ func (a *Agent) Run(ctx context.Context) error {
 	}
 
 	log.Printf("[agent] Initializing plugins")
-	err := a.initPlugins()
+	pipelines, err := a.initPlugins(a.Config.AllPipelines())
+	defer stopProcessors(a.Config.AllPipelines())
 	if err != nil {
 		return err
 	}
 
 	log.Printf("[agent] Connecting outputs")
-	err = a.connectOutput(ctx)
+	pipelines, err = a.connectOutput(ctx, pipelines)
 	if err != nil {
 		return err
 	}
 
-	inputC := make(chan internal.Metric, 100)
-
-	startTime := time.Now()
+	startTime := a.Clock.Now()
 
 	var wg sync.WaitGroup
+	for _, pipeline := range pipelines {
+		wg.Add(1)
+		go func(pipeline *config.Pipeline) {
+			defer wg.Done()
+			a.runPipeline(ctx, startTime, pipeline)
+		}(pipeline)
+	}
+	wg.Wait()
+
+	log.Printf("[agent] Closing outputs")
+	closeOutputs(pipelines)
 
-	src := inputC
-	dst := inputC
+	log.Printf("[agent] Stopped Successfully")
+	return nil
+}
+
+// runPipeline runs the inputs and outputs of a pipeline, connected by their
+// own metric channel, until the context is done.
+func (a *Agent) runPipeline(
+	ctx context.Context,
+	startTime time.Time,
+	pipeline *config.Pipeline,
+) {
+	metrics := make(chan internal.Metric, a.Config.Agent.MetricChannelSize)
+
+	var wg sync.WaitGroup
 
 	wg.Add(1)
 	go func(dst chan internal.Metric) {
 		defer wg.Done()
 
-		err := a.runInputs(ctx, startTime, dst)
+		err := a.runInputs(ctx, startTime, pipeline.Inputs, dst)
 		if err != nil {
-			log.Printf("[agent] Error running inputs: %v", err)
+			log.Printf("[agent] [%s] Error running inputs: %v", pipeline.LogName(), err)
 		}
 
 		close(dst)
-		log.Printf("[agent] Input channel closed")
-	}(dst)
-
-	src = dst
+		log.Printf("[agent] [%s] Input channel closed", pipeline.LogName())
+	}(metrics)
 
 	wg.Add(1)
 	go func(src chan internal.Metric) {
 		defer wg.Done()
 
-		err := a.runOutputs(startTime, src)
+		err := a.runOutputs(startTime, pipeline, src)
 		if err != nil {
-			log.Printf("[agent] Error running outputs: %v", err)
+			log.Printf("[agent] [%s] Error running outputs: %v", pipeline.LogName(), err)
 		}
-	}(src)
+	}(metrics)
 
 	wg.Wait()
-
-	log.Printf("[agent] Closing outputs")
-	a.closeOutputs()
-
-	log.Printf("[agent] Stopped Successfully")
-	return nil
 }
 
 // runInputs starts and triggers the periodic gather for Inputs.
 //
+// Inputs with the drop_oldest policy send to a queue of their own, forwarded
+// to dst, so that they only ever drop their own metrics.
+//
 // When the context is done the timers are stopped and this function returns
 // after all ongoing Gather calls complete.
 func (a *Agent) runInputs(
 	ctx context.Context,
 	startTime time.Time,
-	dst chan<- internal.Metric,
+	inputs []*models.RunningInput,
+	dst chan internal.Metric,
 ) error {
 	var wg sync.WaitGroup
-	for _, input := range a.Config.Inputs {
-		interval := a.Config.Agent.Interval.Duration
-		jitter := a.Config.Agent.CollectionJitter.Duration
-
-		// Overwrite agent interval if this plugin has its own.
-		if input.Config.Interval != 0 {
-			interval = input.Config.Interval
+	for _, input := range inputs {
+		sched, jitter := a.inputSchedule(input)
+
+		queue := dst
+		if input.OverflowPolicy() == models.OverflowDropOldest {
+			queue = make(chan internal.Metric, a.Config.Agent.MetricChannelSize)
+			wg.Add(1)
+			go func(queue chan internal.Metric) {
+				defer wg.Done()
+				for m := range queue {
+					dst <- m
+				}
+			}(queue)
 		}
 
-		acc := NewAccumulator(input, dst)
+		acc := NewAccumulator(input, queue, a.Clock)
 		acc.SetPrecision(a.Precision())
 
+		// Unrounded intervals start right away, other schedules wait for
+		// their first activation.
+		next := startTime
+		if input.Config.Schedule != nil || a.roundInterval(input) {
+			next = sched.Next(startTime.Add(-time.Nanosecond))
+		}
+
 		wg.Add(1)
 		go func(input *models.RunningInput) {
 			defer wg.Done()
 
-			if a.Config.Agent.RoundInterval {
-				err := internal.SleepContext(ctx, internal.AlignDuration(startTime, interval))
+			push := func(m internal.Metric) { dst <- m }
+			aggWg := a.startAggregators(ctx, input.Aggregators, push)
+			a.gatherOnSchedule(ctx, acc, input, sched, next, jitter)
+			aggWg.Wait()
+
+			// Push the partial period once the last gather is done.
+			pushAggregators(input.Aggregators, push)
+			if queue != dst {
+				close(queue)
+			}
+		}(input)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// startAggregators runs each aggregator's push at the end of its periods,
+// after its delay, until the context is done.
+func (a *Agent) startAggregators(
+	ctx context.Context,
+	aggregators models.RunningAggregators,
+	push func(internal.Metric),
+) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for _, aggregator := range aggregators {
+		aggregator.Start(a.Clock.Now())
+
+		wg.Add(1)
+		go func(aggregator *models.RunningAggregator) {
+			defer wg.Done()
+			for {
+				wait := aggregator.PeriodEnd().Add(aggregator.Config.Delay).Sub(a.Clock.Now())
+				err := internal.SleepClock(ctx, a.Clock, wait)
 				if err != nil {
 					return
 				}
+				for _, m := range aggregator.Push() {
+					push(m)
+				}
 			}
+		}(aggregator)
+	}
+	return &wg
+}
 
-			a.gatherOnInterval(ctx, acc, input, interval, jitter)
-		}(input)
+// pushAggregators pushes the current period of each aggregator and the
+// metrics it still holds, when the aggregators are stopped.
+func pushAggregators(aggregators models.RunningAggregators, push func(internal.Metric)) {
+	for _, aggregator := range aggregators {
+		for _, m := range aggregator.Flush() {
+			push(m)
+		}
 	}
-	wg.Wait()
+}
 
-	return nil
+// inputSchedule returns the schedule and collection jitter of an input,
+// applying the agent defaults for settings the input does not override.
+func (a *Agent) inputSchedule(input *models.RunningInput) (schedule.Schedule, time.Duration) {
+	jitter := a.Config.Agent.CollectionJitter.Duration
+	if input.Config.CollectionJitter != nil {
+		jitter = *input.Config.CollectionJitter
+	}
+
+	if input.Config.Schedule != nil {
+		return input.Config.Schedule, jitter
+	}
+
+	interval := a.Config.Agent.Interval.Duration
+	// Overwrite agent interval if this plugin has its own.
+	if input.Config.Interval != 0 {
+		interval = input.Config.Interval
+	}
+
+	offset := a.Config.Agent.CollectionOffset.Duration
+	if input.Config.CollectionOffset != nil {
+		offset = *input.Config.CollectionOffset
+	}
+
+	return schedule.Every(interval, offset, a.roundInterval(input)), jitter
+}
+
+func (a *Agent) roundInterval(input *models.RunningInput) bool {
+	if input.Config.RoundInterval != nil {
+		return *input.Config.RoundInterval
+	}
+	return a.Config.Agent.RoundInterval
 }
 
-// gather runs an input's gather function periodically until the context is done.
-func (a *Agent) gatherOnInterval(
+// gatherOnSchedule runs an input's gather function at each activation of
+// its schedule, starting at next, until the context is done.  Activations
+// missed while a gather is still running are skipped, so gathers of an input
+// never overlap.
+func (a *Agent) gatherOnSchedule(
 	ctx context.Context,
 	acc plugins.Accumulator,
 	input *models.RunningInput,
-	interval time.Duration,
+	sched schedule.Schedule,
+	next time.Time,
 	jitter time.Duration,
 ) {
 	defer panicRecover(input)
 
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	for {
-		err := internal.SleepContext(ctx, internal.RandomDuration(jitter))
+	for !next.IsZero() {
+		wait := next.Sub(a.Clock.Now()) + internal.RandomDuration(jitter)
+		err := internal.SleepClock(ctx, a.Clock, wait)
 		if err != nil {
 			return
 		}
 
-		err = a.gatherOnce(acc, input, interval)
+		// The following activation bounds how long the gather may take
+		// before a warning is logged.
+		following := sched.Next(next)
+		timeout := following.Sub(next)
+		if following.IsZero() {
+			timeout = a.Config.Agent.Interval.Duration
+		}
+
+		err = a.gatherOnce(acc, input, timeout)
 		if err != nil {
 			acc.AddError(err)
 		}
 
-		select {
-		case <-ticker.C:
-			continue
-		case <-ctx.Done():
-			return
+		next = following
+		if now := a.Clock.Now(); !next.IsZero() && !next.After(now) {
+			next = sched.Next(now)
 		}
 	}
+
+	log.Printf("[agent] [%s] schedule has no further activations", input.LogName())
 }
 
 // gatherOnce runs the input's Gather function once, logging a warning each interval it fails to complete before.
@@ -191,21 +311,22 @@ func (a *Agent) gatherOnce(
 	}
 }
 
-// runOutputs triggers the periodic write for Outputs.
+// runOutputs triggers the periodic write for the Outputs of a pipeline.
 
 // Runs until src is closed and all metrics have been processed.  Will call
 // Write one final time before returning.
 func (a *Agent) runOutputs(
 	startTime time.Time,
+	pipeline *config.Pipeline,
 	src <-chan internal.Metric,
 ) error {
-	interval := a.Config.Agent.FlushInterval.Duration
-	jitter := a.Config.Agent.FlushJitter.Duration
+	interval := pipeline.FlushInterval
+	jitter := pipeline.FlushJitter
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	var wg sync.WaitGroup
-	for _, output := range a.Config.Outputs {
+	for _, output := range pipeline.Outputs {
 		interval := interval
 		// Overwrite agent flush_interval if this plugin has its own.
 		if output.Config.FlushInterval != 0 {
@@ -225,6 +346,12 @@ func (a *Agent) runOutputs(
 			if a.Config.Agent.RoundInterval {
 				err := internal.SleepContext(ctx, internal.AlignDuration(startTime, interval))
 				if err != nil {
+					// Stopped before the first flush, still write what
+					// has been buffered.
+					err := a.flushOnce(output, interval, output.Write)
+					if err != nil {
+						log.Printf("[agent] Error writing to %s: %v", output.LogName(), err)
+					}
 					return
 				}
 			}
@@ -233,9 +360,17 @@ func (a *Agent) runOutputs(
 		}(output)
 	}
 
+	// Aggregators stop before the final flush so that it includes their
+	// partial periods.
+	aggCtx, aggCancel := context.WithCancel(context.Background())
+	aggWgs := make([]*sync.WaitGroup, len(pipeline.Outputs))
+	for i, output := range pipeline.Outputs {
+		aggWgs[i] = a.startAggregators(aggCtx, output.Aggregators, output.AddAggregate)
+	}
+
 	for metric := range src {
-		for i, output := range a.Config.Outputs {
-			if i == len(a.Config.Outputs)-1 {
+		for i, output := range pipeline.Outputs {
+			if i == len(pipeline.Outputs)-1 {
 				output.AddMetric(metric)
 			} else {
 				output.AddMetric(metric.Copy())
@@ -243,6 +378,12 @@ func (a *Agent) runOutputs(
 		}
 	}
 
+	aggCancel()
+	for i, output := range pipeline.Outputs {
+		aggWgs[i].Wait()
+		pushAggregators(output.Aggregators, output.AddAggregate)
+	}
+
 	log.Println("[agent] Hang on, flushing any cached metrics before shutdown")
 	cancel()
 	wg.Wait()
@@ -320,42 +461,159 @@ func (a *Agent) flushOnce(
 	}
 }
 
-// initPlugins runs the Init function on plugins.
-func (a *Agent) initPlugins() error {
-	for _, input := range a.Config.Inputs {
+// initPlugins runs the Init function on the plugins of each pipeline and
+// returns the pipelines that initialized.  A pipeline with a plugin that
+// fails is disabled so that it does not stop the others; the error is only
+// returned if no pipeline is left.
+func (a *Agent) initPlugins(pipelines []*config.Pipeline) ([]*config.Pipeline, error) {
+	var active []*config.Pipeline
+	var firstErr error
+	for _, pipeline := range pipelines {
+		err := initPipeline(pipeline)
+		if err != nil {
+			log.Errorf("[agent] [%s] Disabling pipeline: %v", pipeline.LogName(), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		active = append(active, pipeline)
+	}
+
+	if len(active) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return active, nil
+}
+
+// initPipeline runs the Init function on the plugins of a pipeline.
+func initPipeline(pipeline *config.Pipeline) error {
+	for _, input := range pipeline.Inputs {
 		err := input.Init()
 		if err != nil {
 			return fmt.Errorf("could not initialize input %s: %v",
 				input.LogName(), err)
 		}
+
+		err = initProcessors(input.Processors)
+		if err != nil {
+			return fmt.Errorf("input %s: %v", input.LogName(), err)
+		}
+
+		err = initAggregators(input.Aggregators)
+		if err != nil {
+			return fmt.Errorf("input %s: %v", input.LogName(), err)
+		}
 	}
 
-	for _, output := range a.Config.Outputs {
+	for _, output := range pipeline.Outputs {
 		err := output.Init()
 		if err != nil {
 			return fmt.Errorf("could not initialize output %s: %v",
 				output.Config.Name, err)
 		}
+
+		err = initProcessors(output.Processors)
+		if err != nil {
+			return fmt.Errorf("output %s: %v", output.LogName(), err)
+		}
+
+		err = initAggregators(output.Aggregators)
+		if err != nil {
+			return fmt.Errorf("output %s: %v", output.LogName(), err)
+		}
 	}
 	return nil
 }
 
-func (a *Agent) connectOutput(ctx context.Context) error {
-	for _, output := range a.Config.Outputs {
+// initProcessors runs the Init function on a chain of processors.
+func initProcessors(processors models.RunningProcessors) error {
+	for _, processor := range processors {
+		err := processor.Init()
+		if err != nil {
+			return fmt.Errorf("could not initialize processor %s: %v",
+				processor.LogName(), err)
+		}
+	}
+	return nil
+}
+
+// stopProcessors runs the Stop function on the processors of the inputs and
+// outputs of the pipelines.
+func stopProcessors(pipelines []*config.Pipeline) {
+	for _, pipeline := range pipelines {
+		for _, input := range pipeline.Inputs {
+			for _, processor := range input.Processors {
+				processor.Stop()
+			}
+		}
+		for _, output := range pipeline.Outputs {
+			for _, processor := range output.Processors {
+				processor.Stop()
+			}
+		}
+	}
+}
+
+// initAggregators runs the Init function on the aggregators of a plugin.
+func initAggregators(aggregators models.RunningAggregators) error {
+	for _, aggregator := range aggregators {
+		err := aggregator.Init()
+		if err != nil {
+			return fmt.Errorf("could not initialize aggregator %s: %v",
+				aggregator.LogName(), err)
+		}
+	}
+	return nil
+}
+
+// connectOutput connects the outputs of each pipeline and returns the
+// pipelines whose outputs all connected.  A pipeline with an output that
+// fails to connect is disabled, as in initPlugins.
+func (a *Agent) connectOutput(ctx context.Context, pipelines []*config.Pipeline) ([]*config.Pipeline, error) {
+	var active []*config.Pipeline
+	var firstErr error
+	for _, pipeline := range pipelines {
+		err := a.connectPipeline(ctx, pipeline)
+		if ctx.Err() != nil {
+			closeOutputs(active)
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			log.Errorf("[agent] [%s] Disabling pipeline: %v", pipeline.LogName(), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		active = append(active, pipeline)
+	}
+
+	if len(active) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return active, nil
+}
+
+// connectPipeline connects the outputs of a pipeline, retrying once after
+// 15s.  If an output fails the outputs already connected are closed.
+func (a *Agent) connectPipeline(ctx context.Context, pipeline *config.Pipeline) error {
+	for i, output := range pipeline.Outputs {
 		log.Printf("[agent] Attempting connection to [%s]", output.LogName())
 		err := output.Output.Connect()
 		if err != nil {
 			log.Printf("[agent] Failed to connect to [%s], retrying in 15s, "+
 				"error was '%s'", output.LogName(), err)
 
-			err := internal.SleepContext(ctx, 15*time.Second)
-			if err != nil {
-				return err
+			err = internal.SleepClock(ctx, a.Clock, 15*time.Second)
+			if err == nil {
+				err = output.Output.Connect()
 			}
-
-			err = output.Output.Connect()
 			if err != nil {
-				return err
+				for _, connected := range pipeline.Outputs[:i] {
+					connected.Close()
+				}
+				return fmt.Errorf("could not connect to %s: %v", output.LogName(), err)
 			}
 		}
 		log.Printf("[agent] Successfully connected to %s", output.LogName())
@@ -363,10 +621,12 @@ func (a *Agent) connectOutput(ctx context.Context) error {
 	return nil
 }
 
-// closeOutputs closes all outputs.
-func (a *Agent) closeOutputs() {
-	for _, output := range a.Config.Outputs {
-		output.Close()
+// closeOutputs closes the outputs of the pipelines.
+func closeOutputs(pipelines []*config.Pipeline) {
+	for _, pipeline := range pipelines {
+		for _, output := range pipeline.Outputs {
+			output.Close()
+		}
 	}
 }
 
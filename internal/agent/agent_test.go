@@ -0,0 +1,279 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/internal/config"
+	"github.com/geekflow/straw/internal/models"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type discardOutput struct{}
+
+func (*discardOutput) Connect() error                  { return nil }
+func (*discardOutput) Close() error                    { return nil }
+func (*discardOutput) Description() string             { return "" }
+func (*discardOutput) SampleConfig() string            { return "" }
+func (*discardOutput) Write(_ []internal.Metric) error { return nil }
+
+type nopMaker struct{}
+
+func (nopMaker) LogName() string { return "inputs.nop" }
+
+func (nopMaker) MakeMetric(m internal.Metric) internal.Metric { return m }
+
+func (nopMaker) Process(m internal.Metric) []internal.Metric { return []internal.Metric{m} }
+
+func (nopMaker) OverflowPolicy() models.OverflowPolicy { return models.OverflowBlock }
+
+func (nopMaker) MetricDropped(internal.Metric) {}
+
+func BenchmarkAccumulatorAddFields(b *testing.B) {
+	metrics := make(chan internal.Metric, 1)
+	acc := NewAccumulator(nopMaker{}, metrics, internal.RealClock)
+
+	tags := map[string]string{"host": "localhost", "cpu": "cpu0"}
+	fields := map[string]interface{}{
+		"usage_user":   0.5,
+		"usage_system": 0.25,
+		"usage_idle":   99.25,
+	}
+	now := time.Now()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		acc.AddFields("cpu", fields, tags, now)
+		<-metrics
+	}
+}
+
+func BenchmarkRunOutputs(b *testing.B) {
+	c := config.NewConfig()
+	c.Agent.RoundInterval = false
+	c.Agent.FlushInterval = internal.Duration{Duration: time.Hour}
+	for i := 0; i < 4; i++ {
+		c.Outputs = append(c.Outputs, models.NewRunningOutput("discard",
+			&discardOutput{}, &models.OutputConfig{Name: "discard"}, 0, 0))
+	}
+	a, _ := NewAgent(c)
+
+	m := testutil.MustMetric("cpu",
+		map[string]string{"host": "localhost", "cpu": "cpu0"},
+		map[string]interface{}{"usage_user": 0.5, "usage_idle": 99.5},
+		time.Now())
+
+	src := make(chan internal.Metric, 100)
+	go func() {
+		for i := 0; i < b.N; i++ {
+			src <- m
+		}
+		close(src)
+	}()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	a.runOutputs(time.Now(), c.DefaultPipeline(), src)
+}
+
+func TestInputScheduleOverrides(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.Interval = internal.Duration{Duration: time.Minute}
+	c.Agent.CollectionJitter = internal.Duration{Duration: time.Second}
+	a := &Agent{Config: c}
+
+	start := time.Unix(90, 0)
+
+	input := models.NewRunningInput(nil, &models.InputConfig{Name: "nop"})
+	sched, jitter := a.inputSchedule(input)
+	require.Equal(t, time.Second, jitter)
+	require.Equal(t, time.Unix(120, 0), sched.Next(start))
+
+	round := false
+	offset := 5 * time.Second
+	noJitter := time.Duration(0)
+	input = models.NewRunningInput(nil, &models.InputConfig{
+		Name:             "nop",
+		RoundInterval:    &round,
+		CollectionJitter: &noJitter,
+		CollectionOffset: &offset,
+	})
+	sched, jitter = a.inputSchedule(input)
+	require.Equal(t, time.Duration(0), jitter)
+	require.Equal(t, time.Unix(150, 0), sched.Next(start))
+
+	round = true
+	sched, _ = a.inputSchedule(input)
+	require.Equal(t, time.Unix(125, 0), sched.Next(start))
+}
+
+type untimedInput struct{}
+
+func (*untimedInput) Description() string  { return "" }
+func (*untimedInput) SampleConfig() string { return "" }
+
+func (*untimedInput) Gather(acc plugins.Accumulator) error {
+	acc.AddFields("cpu", map[string]interface{}{"value": 42.0}, nil)
+	return nil
+}
+
+func TestRunInputsFollowsClock(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.RoundInterval = true
+	c.Agent.Interval = internal.Duration{Duration: 10 * time.Second}
+	c.Agent.Precision = internal.Duration{Duration: time.Second}
+	a, _ := NewAgent(c)
+
+	clock := testutil.NewClock(time.Unix(5, 0))
+	a.Clock = clock
+
+	input := models.NewRunningInput(&untimedInput{}, &models.InputConfig{Name: "untimed"})
+	dst := make(chan internal.Metric, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		a.runInputs(ctx, clock.Now(), []*models.RunningInput{input}, dst)
+		close(done)
+	}()
+
+	for _, want := range []time.Time{time.Unix(10, 0), time.Unix(20, 0)} {
+		clock.BlockUntil(1)
+		require.Len(t, dst, 0)
+		clock.Set(want)
+
+		m := <-dst
+		require.Equal(t, want, m.Time())
+	}
+
+	cancel()
+	<-done
+}
+
+type burstInput struct {
+	count int
+}
+
+func (*burstInput) Description() string  { return "" }
+func (*burstInput) SampleConfig() string { return "" }
+
+func (b *burstInput) Gather(acc plugins.Accumulator) error {
+	for i := 0; i < b.count; i++ {
+		acc.AddFields("burst", map[string]interface{}{"value": i}, nil)
+	}
+	return nil
+}
+
+func TestRunInputsDropOldestOwnQueue(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.RoundInterval = false
+	c.Agent.Interval = internal.Duration{Duration: time.Hour}
+	c.Agent.MetricChannelSize = 2
+	a, _ := NewAgent(c)
+	a.Clock = testutil.NewClock(time.Unix(0, 0))
+
+	blocking := models.NewRunningInput(&untimedInput{}, &models.InputConfig{
+		Name: "test_queue_block",
+	})
+	dropping := models.NewRunningInput(&burstInput{count: 5}, &models.InputConfig{
+		Name:           "test_queue_drop_oldest",
+		OverflowPolicy: models.OverflowDropOldest,
+	})
+	dst := make(chan internal.Metric, 1)
+
+	// The drop counters are process-wide.
+	blockingDropped := blocking.MetricsDropped()
+	droppingDropped := dropping.MetricsDropped()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		a.runInputs(ctx, a.Clock.Now(), []*models.RunningInput{blocking, dropping}, dst)
+		close(done)
+	}()
+
+	// Nothing reads dst: the burst overflows the queue of its input, which
+	// holds 2 metrics, while the forwarder waits with a third.
+	require.Eventually(t, func() bool { return dropping.MetricsDropped() > droppingDropped },
+		time.Second, time.Millisecond)
+	cancel()
+
+	var names []string
+	for {
+		select {
+		case m := <-dst:
+			names = append(names, m.Name())
+			continue
+		case <-done:
+		}
+		break
+	}
+	for len(dst) > 0 {
+		names = append(names, (<-dst).Name())
+	}
+
+	// The blocking input waited for room, none of its metrics were dropped.
+	require.Equal(t, blockingDropped, blocking.MetricsDropped())
+	require.Contains(t, names, "cpu")
+	require.Equal(t, int64(5), int64(len(names)-1)+dropping.MetricsDropped()-droppingDropped)
+}
+
+type failingOutput struct {
+	discardOutput
+}
+
+func (*failingOutput) Connect() error { return errors.New("connection refused") }
+
+func TestRunDisablesFailedPipeline(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.RoundInterval = false
+	c.Agent.Interval = internal.Duration{Duration: time.Hour}
+	c.Agent.FlushInterval = internal.Duration{Duration: time.Hour}
+
+	newPipeline := func(name string, output plugins.Output) *config.Pipeline {
+		p := c.DefaultPipeline()
+		p.Name = name
+		p.Tags = map[string]string{"pipeline": name}
+		input := models.NewRunningInput(&constInput{}, &models.InputConfig{Name: "const"})
+		input.SetDefaultTags(p.Tags)
+		p.Inputs = []*models.RunningInput{input}
+		p.Outputs = []*models.RunningOutput{models.NewRunningOutput(name, output,
+			&models.OutputConfig{Name: name}, 0, 0)}
+		return p
+	}
+	output := &captureOutput{}
+	c.Pipelines = []*config.Pipeline{
+		newPipeline("broken", &failingOutput{}),
+		newPipeline("working", output),
+	}
+
+	a, _ := NewAgent(c)
+	clock := testutil.NewClock(time.Unix(0, 0))
+	a.Clock = clock
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- a.Run(ctx)
+	}()
+
+	// Skip the retry of the broken output.
+	clock.BlockUntil(1)
+	clock.Add(15 * time.Second)
+
+	// Wait for the gather of the working pipeline.
+	clock.BlockUntil(1)
+	cancel()
+	require.NoError(t, <-done)
+
+	expected := []internal.Metric{
+		testutil.MustMetric("cpu", map[string]string{"cpu": "cpu0", "pipeline": "working"},
+			map[string]interface{}{"value": 42.0}, time.Unix(1, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, output.metrics)
+}
@@ -0,0 +1,247 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/internal/config"
+	"github.com/geekflow/straw/metric"
+	parsers "github.com/geekflow/straw/plugins/parsers/influx"
+	"github.com/geekflow/straw/plugins/serializers/influx"
+	"io"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The pipeline and the value type of recorded metrics are kept in these
+// tags, which are removed again on replay.  They are left out for metrics of
+// the default pipeline and untyped metrics.
+const (
+	pipelineTag  = "_straw_pipeline"
+	valueTypeTag = "_straw_type"
+)
+
+var valueTypeNames = map[internal.ValueType]string{
+	internal.Counter:   "counter",
+	internal.Gauge:     "gauge",
+	internal.Summary:   "summary",
+	internal.Histogram: "histogram",
+}
+
+// Record runs the inputs of all pipelines until the context is done and
+// writes every metric they produce to w as line protocol, tagged with its
+// pipeline and value type.  No outputs are run.
+func (a *Agent) Record(ctx context.Context, w io.Writer) error {
+	log.Printf("[agent] Initializing plugins")
+	pipelines, err := a.initPlugins(a.Config.AllPipelines())
+	defer stopProcessors(a.Config.AllPipelines())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	metrics := make(chan internal.Metric, a.Config.Agent.MetricChannelSize)
+	startTime := a.Clock.Now()
+
+	var wg sync.WaitGroup
+	for _, pipeline := range pipelines {
+		src := make(chan internal.Metric, a.Config.Agent.MetricChannelSize)
+
+		wg.Add(1)
+		go func(pipeline *config.Pipeline) {
+			defer wg.Done()
+
+			err := a.runInputs(ctx, startTime, pipeline.Inputs, src)
+			if err != nil {
+				log.Printf("[agent] [%s] Error running inputs: %v", pipeline.LogName(), err)
+			}
+			close(src)
+		}(pipeline)
+
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+
+			for m := range src {
+				if name != "" {
+					m.AddTag(pipelineTag, name)
+				}
+				if tp, ok := valueTypeNames[m.Type()]; ok {
+					m.AddTag(valueTypeTag, tp)
+				}
+				metrics <- m
+			}
+		}(pipeline.Name)
+	}
+
+	go func() {
+		wg.Wait()
+		close(metrics)
+	}()
+
+	serializer := influx.NewSerializer()
+	serializer.SetFieldTypeSupport(influx.UintSupport)
+
+	bw := bufio.NewWriter(w)
+	var count int
+	for m := range metrics {
+		_, err := serializer.Write(bw, m)
+		if err != nil {
+			if _, ok := err.(*influx.MetricError); !ok {
+				// Stop the inputs and drain the channel so they can exit.
+				cancel()
+				for range metrics {
+				}
+				return err
+			}
+			log.Printf("[agent] Could not record metric: %v", err)
+			m.Drop()
+			continue
+		}
+		m.Accept()
+		count++
+	}
+
+	log.Printf("[agent] Recorded %d metrics", count)
+	return bw.Flush()
+}
+
+// Replay reads line protocol from r and writes each metric to the outputs
+// of the pipeline it was recorded in.  Metrics of pipelines missing from the
+// configuration are skipped.  If preserveTiming is set the metrics are sent
+// with the same spacing as their timestamps, otherwise as fast as the
+// outputs accept them.  Replay returns once all metrics are written or the
+// context is done.
+func (a *Agent) Replay(ctx context.Context, r io.Reader, preserveTiming bool) error {
+	log.Printf("[agent] Initializing plugins")
+	pipelines, err := a.initPlugins(a.Config.AllPipelines())
+	defer stopProcessors(a.Config.AllPipelines())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[agent] Connecting outputs")
+	pipelines, err = a.connectOutput(ctx, pipelines)
+	if err != nil {
+		return err
+	}
+
+	startTime := a.Clock.Now()
+
+	var wg sync.WaitGroup
+	channels := make(map[string]chan internal.Metric, len(pipelines))
+	for _, pipeline := range pipelines {
+		src := make(chan internal.Metric, a.Config.Agent.MetricChannelSize)
+		channels[pipeline.Name] = src
+
+		wg.Add(1)
+		go func(pipeline *config.Pipeline) {
+			defer wg.Done()
+
+			err := a.runOutputs(startTime, pipeline, src)
+			if err != nil {
+				log.Printf("[agent] [%s] Error running outputs: %v", pipeline.LogName(), err)
+			}
+		}(pipeline)
+	}
+
+	err = a.replay(ctx, r, preserveTiming, channels)
+
+	for _, dst := range channels {
+		close(dst)
+	}
+	wg.Wait()
+
+	log.Printf("[agent] Closing outputs")
+	closeOutputs(pipelines)
+	return err
+}
+
+// replay sends each parsed metric to the channel of its pipeline.
+func (a *Agent) replay(
+	ctx context.Context,
+	r io.Reader,
+	preserveTiming bool,
+	channels map[string]chan internal.Metric,
+) error {
+	parser := parsers.NewParser().NewStreamParser(r)
+
+	var first time.Time
+	var count int
+	skipped := make(map[string]int)
+	start := a.Clock.Now()
+	for {
+		m, err := parser.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if _, ok := err.(*parsers.ParseError); ok {
+				log.Printf("[agent] Skipping metric: %v", err)
+				continue
+			}
+			return err
+		}
+
+		name, m, err := restoreCaptured(m)
+		if err != nil {
+			log.Printf("[agent] Skipping metric: %v", err)
+			continue
+		}
+		dst, ok := channels[name]
+		if !ok {
+			skipped[name]++
+			continue
+		}
+
+		if preserveTiming {
+			if first.IsZero() {
+				first = m.Time()
+			}
+			wait := m.Time().Sub(first) - a.Clock.Now().Sub(start)
+			err := internal.SleepClock(ctx, a.Clock, wait)
+			if err != nil {
+				return err
+			}
+		}
+
+		select {
+		case dst <- m:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		count++
+	}
+
+	for name, n := range skipped {
+		log.Printf("[agent] Skipped %d metrics of pipeline %q, which is not configured", n, name)
+	}
+	log.Printf("[agent] Replayed %d metrics", count)
+	return nil
+}
+
+// restoreCaptured removes the tags added by Record from a metric and
+// returns its pipeline name and the metric with its value type.
+func restoreCaptured(m internal.Metric) (string, internal.Metric, error) {
+	name, _ := m.GetTag(pipelineTag)
+	m.RemoveTag(pipelineTag)
+
+	tpName, ok := m.GetTag(valueTypeTag)
+	if !ok {
+		return name, m, nil
+	}
+	m.RemoveTag(valueTypeTag)
+
+	for tp, n := range valueTypeNames {
+		if n == tpName {
+			typed, err := metric.New(m.Name(), m.Tags(), m.Fields(), m.Time(), tp)
+			return name, typed, err
+		}
+	}
+	return "", nil, fmt.Errorf("unknown value type %q", tpName)
+}
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/internal/config"
+	"github.com/geekflow/straw/internal/models"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type captureOutput struct {
+	sync.Mutex
+	metrics []internal.Metric
+}
+
+func (*captureOutput) Connect() error       { return nil }
+func (*captureOutput) Close() error         { return nil }
+func (*captureOutput) Description() string  { return "" }
+func (*captureOutput) SampleConfig() string { return "" }
+
+func (o *captureOutput) Write(metrics []internal.Metric) error {
+	o.Lock()
+	defer o.Unlock()
+	o.metrics = append(o.metrics, metrics...)
+	return nil
+}
+
+type constInput struct{}
+
+func (*constInput) Description() string  { return "" }
+func (*constInput) SampleConfig() string { return "" }
+
+func (*constInput) Gather(acc plugins.Accumulator) error {
+	acc.AddFields("cpu",
+		map[string]interface{}{"value": 42.0},
+		map[string]string{"cpu": "cpu0"},
+		time.Unix(1, 0))
+	return nil
+}
+
+type counterInput struct{}
+
+func (*counterInput) Description() string  { return "" }
+func (*counterInput) SampleConfig() string { return "" }
+
+func (*counterInput) Gather(acc plugins.Accumulator) error {
+	acc.AddCounter("procs",
+		map[string]interface{}{"total": int64(3)},
+		nil,
+		time.Unix(2, 0))
+	return nil
+}
+
+func TestRecord(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.RoundInterval = false
+	c.Agent.Interval = internal.Duration{Duration: time.Hour}
+	c.Inputs = append(c.Inputs, models.NewRunningInput(&constInput{},
+		&models.InputConfig{Name: "const"}))
+
+	security := c.DefaultPipeline()
+	security.Name = "security"
+	security.Inputs = []*models.RunningInput{models.NewRunningInput(&counterInput{},
+		&models.InputConfig{Name: "counter"})}
+	security.Outputs = nil
+	c.Pipelines = append(c.Pipelines, security)
+	a, _ := NewAgent(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var buf bytes.Buffer
+	require.NoError(t, a.Record(ctx, &buf))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	sort.Strings(lines)
+	require.Equal(t, []string{
+		"cpu,cpu=cpu0 value=42 1000000000",
+		"procs,_straw_pipeline=security,_straw_type=counter total=3i 2000000000",
+	}, lines)
+}
+
+func TestReplay(t *testing.T) {
+	output := &captureOutput{}
+	c := config.NewConfig()
+	c.Outputs = append(c.Outputs, models.NewRunningOutput("capture", output,
+		&models.OutputConfig{Name: "capture", BufferOrder: models.BufferOrderFIFO}, 0, 0))
+
+	securityOutput := &captureOutput{}
+	security := c.DefaultPipeline()
+	security.Name = "security"
+	security.Inputs = nil
+	security.Outputs = []*models.RunningOutput{models.NewRunningOutput("capture", securityOutput,
+		&models.OutputConfig{Name: "capture", BufferOrder: models.BufferOrderFIFO}, 0, 0)}
+	c.Pipelines = append(c.Pipelines, security)
+	a, _ := NewAgent(c)
+
+	capture := "cpu,cpu=cpu0 value=42 1000000000\n" +
+		"not line protocol\n" +
+		"procs,_straw_pipeline=security,_straw_type=counter total=3i 2000000000\n" +
+		"mem,_straw_pipeline=unknown used=1i 3000000000\n" +
+		"cpu,cpu=cpu0,_straw_type=gauge value=43 4000000000\n"
+	err := a.Replay(context.Background(), strings.NewReader(capture), false)
+	require.NoError(t, err)
+
+	expected := []internal.Metric{
+		testutil.MustMetric("cpu", map[string]string{"cpu": "cpu0"},
+			map[string]interface{}{"value": 42.0}, time.Unix(1, 0)),
+		testutil.MustMetric("cpu", map[string]string{"cpu": "cpu0"},
+			map[string]interface{}{"value": 43.0}, time.Unix(4, 0), internal.Gauge),
+	}
+	testutil.RequireMetricsEqual(t, expected, output.metrics)
+
+	expected = []internal.Metric{
+		testutil.MustMetric("procs", map[string]string{},
+			map[string]interface{}{"total": int64(3)}, time.Unix(2, 0), internal.Counter),
+	}
+	testutil.RequireMetricsEqual(t, expected, securityOutput.metrics)
+}
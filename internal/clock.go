@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"context"
+	"time"
+)
+
+// Clock tells the current time and waits for time to pass.  The agent and
+// its accumulators take the time from a Clock so that tests can control it.
+type Clock interface {
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current
+	// time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// RealClock is the Clock of the system.
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// SleepClock waits on the clock for the duration or until the context is
+// done.  Returns the context error if the context is done.
+func SleepClock(ctx context.Context, clock Clock, duration time.Duration) error {
+	if duration <= 0 {
+		return ctx.Err()
+	}
+
+	if clock == RealClock {
+		return SleepContext(ctx, duration)
+	}
+
+	select {
+	case <-clock.After(duration):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"github.com/geekflow/straw/internal"
 	"github.com/geekflow/straw/internal/models"
+	"github.com/geekflow/straw/internal/schedule"
+	"github.com/geekflow/straw/plugins/aggregators"
 	"github.com/geekflow/straw/plugins/inputs"
 	"github.com/geekflow/straw/plugins/outputs"
+	"github.com/geekflow/straw/plugins/processors"
 	serializers "github.com/geekflow/straw/plugins/serializers"
 	"github.com/influxdata/toml"
 	"github.com/influxdata/toml/ast"
@@ -48,6 +51,13 @@ type Config struct {
 	Agent   *AgentConfig
 	Inputs  []*models.RunningInput
 	Outputs []*models.RunningOutput
+
+	// Pipelines are the named pipelines defined with [[pipeline]] tables.
+	// The top-level inputs and outputs form the default pipeline.
+	Pipelines []*Pipeline
+
+	// inputInstances counts the inputs loaded by pipeline, name and alias.
+	inputInstances map[string]int
 }
 
 type AgentConfig struct {
@@ -59,11 +69,19 @@ type AgentConfig struct {
 	Precision        internal.Duration
 	FlushInterval    internal.Duration
 	CollectionJitter internal.Duration
+	// CollectionOffset shifts the rounded collection times by a fixed
+	// amount, ie, if Interval=1m and CollectionOffset=5s then collect on
+	// :05 of each minute.
+	CollectionOffset internal.Duration
 	FlushJitter      internal.Duration
 
 	MetricBatchSize   int
 	MetricBufferLimit int
 
+	// MetricChannelSize is the capacity of the channel between the inputs
+	// and the outputs, and of the queue of each drop_oldest input.
+	MetricChannelSize int
+
 	// Quiet is the option for running in quiet mode
 	Quiet bool `toml:"quiet"`
 
@@ -114,6 +132,8 @@ func NewConfig() *Config {
 			RoundInterval: true,
 			FlushInterval: internal.Duration{Duration: 10 * time.Second},
 			LogTarget:     "file",
+
+			MetricChannelSize: 100,
 		},
 
 		Tags:    make(map[string]string),
@@ -230,6 +250,19 @@ func (c *Config) LoadConfig(path string) error {
 
 	// Parse all the rest of the plugins:
 	for name, val := range tbl.Fields {
+		if name == "pipeline" {
+			tables, ok := val.([]*ast.Table)
+			if !ok {
+				return fmt.Errorf("%s: invalid configuration, expected [[pipeline]]", path)
+			}
+			for _, t := range tables {
+				if err = c.addPipeline(t); err != nil {
+					return fmt.Errorf("Error parsing %s, %s", path, err)
+				}
+			}
+			continue
+		}
+
 		subTable, ok := val.(*ast.Table)
 		if !ok {
 			return fmt.Errorf("%s: invalid configuration", path)
@@ -357,35 +390,96 @@ func parseConfig(contents []byte) (*ast.Table, error) {
 }
 
 func (c *Config) addInput(name string, table *ast.Table) error {
+	rp, err := c.newInput(name, table, "", c.Tags)
+	if err != nil {
+		return err
+	}
+
+	c.Inputs = append(c.Inputs, rp)
+	return nil
+}
+
+// newInput creates the input plugin and its RunningInput from the table, for
+// the named pipeline or the default pipeline if pipeline is empty.
+func (c *Config) newInput(
+	name string,
+	table *ast.Table,
+	pipeline string,
+	tags map[string]string,
+) (*models.RunningInput, error) {
 	creator, ok := inputs.Inputs[name]
 	if !ok {
-		return fmt.Errorf("undefined but requested input: %s", name)
+		return nil, fmt.Errorf("undefined but requested input: %s", name)
 	}
 	input := creator()
 
+	procs, err := buildProcessors(table)
+	if err != nil {
+		return nil, fmt.Errorf("input %s: %v", name, err)
+	}
+
+	aggs, err := buildAggregators(table)
+	if err != nil {
+		return nil, fmt.Errorf("input %s: %v", name, err)
+	}
+
 	pluginConfig, err := buildInput(name, table)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := toml.UnmarshalTable(table, input); err != nil {
-		return err
+		return nil, err
 	}
 
+	if c.inputInstances == nil {
+		c.inputInstances = make(map[string]int)
+	}
+	key := pipeline + "\x00" + name + "\x00" + pluginConfig.Alias
+	c.inputInstances[key]++
+	pluginConfig.Pipeline = pipeline
+	pluginConfig.Instance = c.inputInstances[key]
+
 	rp := models.NewRunningInput(input, pluginConfig)
-	rp.SetDefaultTags(c.Tags)
-	c.Inputs = append(c.Inputs, rp)
+	rp.SetDefaultTags(tags)
+	rp.Processors = procs
+	rp.Aggregators = aggs
+	return rp, nil
+}
 
+func (c *Config) addOutput(name string, table *ast.Table) error {
+	ro, err := newOutput(name, table, c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+	if err != nil {
+		return err
+	}
+
+	c.Outputs = append(c.Outputs, ro)
 	return nil
 }
 
-func (c *Config) addOutput(name string, table *ast.Table) error {
+// newOutput creates the output plugin and its RunningOutput from the table.
+func newOutput(
+	name string,
+	table *ast.Table,
+	batchSize int,
+	bufferLimit int,
+) (*models.RunningOutput, error) {
 	creator, ok := outputs.Outputs[name]
 	if !ok {
-		return fmt.Errorf("undefined but requested output: %s", name)
+		return nil, fmt.Errorf("undefined but requested output: %s", name)
 	}
 	output := creator()
 
+	procs, err := buildProcessors(table)
+	if err != nil {
+		return nil, fmt.Errorf("output %s: %v", name, err)
+	}
+
+	aggs, err := buildAggregators(table)
+	if err != nil {
+		return nil, fmt.Errorf("output %s: %v", name, err)
+	}
+
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
 	switch t := output.(type) {
@@ -393,24 +487,223 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 		serializer, err := buildSerializer(name, table)
 
 		if err != nil {
-			return err
+			return nil, err
 		}
 		t.SetSerializer(serializer)
 	}
 
 	outputConfig, err := buildOutput(name, table)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := toml.UnmarshalTable(table, output); err != nil {
-		return err
+		return nil, err
 	}
 
-	ro := models.NewRunningOutput(name, output, outputConfig,
-		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
-	c.Outputs = append(c.Outputs, ro)
-	return nil
+	ro := models.NewRunningOutput(name, output, outputConfig, batchSize, bufferLimit)
+	ro.Processors = procs
+	ro.Aggregators = aggs
+	return ro, nil
+}
+
+// buildProcessors removes the processors table of an input or output and
+// creates the processors defined in it, sorted by their order, then in the
+// order they are declared.
+func buildProcessors(tbl *ast.Table) (models.RunningProcessors, error) {
+	node, ok := tbl.Fields["processors"]
+	if !ok {
+		return nil, nil
+	}
+	delete(tbl.Fields, "processors")
+
+	subTable, ok := node.(*ast.Table)
+	if !ok {
+		return nil, fmt.Errorf("invalid processors configuration")
+	}
+
+	var procs models.RunningProcessors
+	err := eachPlugin(subTable, func(name string, t *ast.Table) error {
+		rp, err := newProcessor(name, t)
+		if err != nil {
+			return err
+		}
+		procs = append(procs, rp)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	procs.Sort()
+	return procs, nil
+}
+
+// newProcessor creates the processor plugin and its RunningProcessor from
+// the table.
+func newProcessor(name string, table *ast.Table) (*models.RunningProcessor, error) {
+	creator, ok := processors.Processors[name]
+	if !ok {
+		return nil, fmt.Errorf("undefined but requested processor: %s", name)
+	}
+	processor := creator()
+
+	processorConfig, err := buildProcessor(name, table)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := toml.UnmarshalTable(table, processor); err != nil {
+		return nil, err
+	}
+
+	return models.NewRunningProcessor(processor, processorConfig), nil
+}
+
+// buildProcessor parses the common processor settings from the ast.Table.
+func buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error) {
+	pc := &models.ProcessorConfig{Name: name}
+
+	if node, ok := tbl.Fields["order"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := integer.Int()
+				if err != nil {
+					return nil, err
+				}
+				pc.Order = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				pc.Alias = str.Value
+			}
+		}
+	}
+
+	delete(tbl.Fields, "order")
+	delete(tbl.Fields, "alias")
+
+	return pc, nil
+}
+
+// buildAggregators removes the aggregators table of an input or output and
+// creates the aggregators defined in it.
+func buildAggregators(tbl *ast.Table) (models.RunningAggregators, error) {
+	node, ok := tbl.Fields["aggregators"]
+	if !ok {
+		return nil, nil
+	}
+	delete(tbl.Fields, "aggregators")
+
+	subTable, ok := node.(*ast.Table)
+	if !ok {
+		return nil, fmt.Errorf("invalid aggregators configuration")
+	}
+
+	var aggs models.RunningAggregators
+	err := eachPlugin(subTable, func(name string, t *ast.Table) error {
+		ra, err := newAggregator(name, t)
+		if err != nil {
+			return err
+		}
+		aggs = append(aggs, ra)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return aggs, nil
+}
+
+// newAggregator creates the aggregator plugin and its RunningAggregator from
+// the table.
+func newAggregator(name string, table *ast.Table) (*models.RunningAggregator, error) {
+	creator, ok := aggregators.Aggregators[name]
+	if !ok {
+		return nil, fmt.Errorf("undefined but requested aggregator: %s", name)
+	}
+	aggregator := creator()
+
+	aggregatorConfig, err := buildAggregator(name, table)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := toml.UnmarshalTable(table, aggregator); err != nil {
+		return nil, err
+	}
+
+	return models.NewRunningAggregator(aggregator, aggregatorConfig), nil
+}
+
+// buildAggregator parses the common aggregator settings from the ast.Table.
+func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, error) {
+	ac := &models.AggregatorConfig{
+		Name:   name,
+		Period: 30 * time.Second,
+		Delay:  100 * time.Millisecond,
+	}
+
+	if node, ok := tbl.Fields["period"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				if dur <= 0 {
+					return nil, fmt.Errorf("aggregator %s: period must be positive", name)
+				}
+
+				ac.Period = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["delay"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				ac.Delay = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["drop_original"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				v, err := b.Boolean()
+				if err != nil {
+					return nil, err
+				}
+
+				ac.DropOriginal = v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				ac.Alias = str.Value
+			}
+		}
+	}
+
+	delete(tbl.Fields, "period")
+	delete(tbl.Fields, "delay")
+	delete(tbl.Fields, "drop_original")
+	delete(tbl.Fields, "alias")
+
+	return ac, nil
 }
 
 func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
@@ -428,6 +721,71 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["schedule"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				sched, err := schedule.ParseCron(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				cp.Schedule = sched
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["round_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				v, err := b.Boolean()
+				if err != nil {
+					return nil, err
+				}
+
+				cp.RoundInterval = &v
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["collection_jitter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				cp.CollectionJitter = &dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["collection_offset"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				cp.CollectionOffset = &dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["overflow_policy"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				policy, err := models.ParseOverflowPolicy(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				cp.OverflowPolicy = policy
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["name_prefix"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -474,6 +832,11 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "alias")
 	delete(tbl.Fields, "interval")
+	delete(tbl.Fields, "schedule")
+	delete(tbl.Fields, "round_interval")
+	delete(tbl.Fields, "collection_jitter")
+	delete(tbl.Fields, "collection_offset")
+	delete(tbl.Fields, "overflow_policy")
 	delete(tbl.Fields, "tags")
 
 	return cp, nil
@@ -538,6 +901,86 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["metric_buffer_limit_bytes"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			var size internal.Size
+			if err := size.UnmarshalTOML([]byte(kv.Value.Source())); err != nil {
+				return nil, err
+			}
+			oc.MetricBufferLimitBytes = size.Size
+		}
+	}
+
+	if node, ok := tbl.Fields["metric_batch_size_bytes"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			var size internal.Size
+			if err := size.UnmarshalTOML([]byte(kv.Value.Source())); err != nil {
+				return nil, err
+			}
+			oc.MetricBatchSizeBytes = size.Size
+		}
+	}
+
+	if node, ok := tbl.Fields["max_series"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := integer.Int()
+				if err != nil {
+					return nil, err
+				}
+				oc.MaxSeries = int(v)
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["max_series_window"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.MaxSeriesWindow = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["max_series_action"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				action, err := models.ParseSeriesAction(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.MaxSeriesAction = action
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["max_series_strip_tags"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						oc.MaxSeriesStripTags = append(oc.MaxSeriesStripTags, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_order"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				order, err := models.ParseBufferOrder(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.BufferOrder = order
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["alias"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -550,6 +993,13 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 	delete(tbl.Fields, "flush_jitter")
 	delete(tbl.Fields, "metric_buffer_limit")
 	delete(tbl.Fields, "metric_batch_size")
+	delete(tbl.Fields, "metric_buffer_limit_bytes")
+	delete(tbl.Fields, "metric_batch_size_bytes")
+	delete(tbl.Fields, "buffer_order")
+	delete(tbl.Fields, "max_series")
+	delete(tbl.Fields, "max_series_window")
+	delete(tbl.Fields, "max_series_action")
+	delete(tbl.Fields, "max_series_strip_tags")
 	delete(tbl.Fields, "alias")
 
 	return oc, nil
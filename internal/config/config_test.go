@@ -0,0 +1,47 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/plugins/aggregators/basicstats"
+	_ "github.com/geekflow/straw/plugins/processors/all"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigProcessors(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/processors.toml"))
+
+	require.Len(t, c.Inputs, 1)
+	procs := c.Inputs[0].Processors
+	require.Len(t, procs, 2)
+	require.Equal(t, "processors.regex::group", procs[0].LogName())
+	require.Equal(t, int64(1), procs[0].Config.Order)
+	require.Equal(t, "processors.regex", procs[1].LogName())
+
+	require.Len(t, c.Outputs, 1)
+	procs = c.Outputs[0].Processors
+	require.Len(t, procs, 2)
+	require.Equal(t, "processors.regex", procs[0].LogName())
+	require.Equal(t, "processors.converter", procs[1].LogName())
+}
+
+func TestLoadConfigAggregators(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/aggregators.toml"))
+
+	require.Len(t, c.Inputs, 1)
+	aggs := c.Inputs[0].Aggregators
+	require.Len(t, aggs, 1)
+	require.Equal(t, time.Minute, aggs[0].Config.Period)
+	require.True(t, aggs[0].Config.DropOriginal)
+	require.Equal(t, []string{"mean", "max"}, aggs[0].Aggregator.(*basicstats.BasicStats).Stats)
+
+	require.Len(t, c.Outputs, 1)
+	aggs = c.Outputs[0].Aggregators
+	require.Len(t, aggs, 1)
+	require.Equal(t, "aggregators.basicstats::all", aggs[0].LogName())
+	require.Equal(t, 30*time.Second, aggs[0].Config.Period)
+	require.Equal(t, 100*time.Millisecond, aggs[0].Config.Delay)
+}
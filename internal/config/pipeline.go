@@ -0,0 +1,224 @@
+package config
+
+import (
+	"fmt"
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/internal/models"
+	"sort"
+	"time"
+
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
+)
+
+// Pipeline is a named set of inputs and outputs.  Metrics gathered by the
+// inputs of a pipeline are only written to the outputs of the same pipeline,
+// and each pipeline has its own metric channel so that a stalled output does
+// not hold up the other pipelines.
+type Pipeline struct {
+	Name string
+	Tags map[string]string
+
+	FlushInterval     time.Duration
+	FlushJitter       time.Duration
+	MetricBatchSize   int
+	MetricBufferLimit int
+
+	Inputs  []*models.RunningInput
+	Outputs []*models.RunningOutput
+}
+
+// DefaultPipeline returns the pipeline made of the top-level inputs and
+// outputs.
+func (c *Config) DefaultPipeline() *Pipeline {
+	return &Pipeline{
+		Tags:              c.Tags,
+		FlushInterval:     c.Agent.FlushInterval.Duration,
+		FlushJitter:       c.Agent.FlushJitter.Duration,
+		MetricBatchSize:   c.Agent.MetricBatchSize,
+		MetricBufferLimit: c.Agent.MetricBufferLimit,
+		Inputs:            c.Inputs,
+		Outputs:           c.Outputs,
+	}
+}
+
+// AllPipelines returns the default pipeline, if it has any plugins, followed
+// by the named pipelines.
+func (c *Config) AllPipelines() []*Pipeline {
+	var pipelines []*Pipeline
+	if len(c.Inputs) > 0 || len(c.Outputs) > 0 {
+		pipelines = append(pipelines, c.DefaultPipeline())
+	}
+	return append(pipelines, c.Pipelines...)
+}
+
+// LogName returns the name of the pipeline for logging.
+func (p *Pipeline) LogName() string {
+	if p.Name == "" {
+		return "pipeline"
+	}
+	return "pipeline." + p.Name
+}
+
+func (p *Pipeline) InputNames() []string {
+	var name []string
+	for _, input := range p.Inputs {
+		name = append(name, input.Config.Name)
+	}
+	return name
+}
+
+func (p *Pipeline) OutputNames() []string {
+	var name []string
+	for _, output := range p.Outputs {
+		name = append(name, output.Config.Name)
+	}
+	return name
+}
+
+// pipelineConfig holds the settings of a [[pipeline]] table.
+type pipelineConfig struct {
+	Name              string
+	FlushInterval     *internal.Duration
+	FlushJitter       *internal.Duration
+	MetricBatchSize   *int
+	MetricBufferLimit *int
+}
+
+// addPipeline parses a [[pipeline]] table.  The pipeline inherits the agent
+// flush settings and global tags unless it sets its own.
+func (c *Config) addPipeline(tbl *ast.Table) error {
+	var subTables = map[string]*ast.Table{}
+	for _, name := range []string{"tags", "inputs", "outputs"} {
+		if val, ok := tbl.Fields[name]; ok {
+			subTable, ok := val.(*ast.Table)
+			if !ok {
+				return fmt.Errorf("pipeline: invalid %s configuration", name)
+			}
+			subTables[name] = subTable
+			delete(tbl.Fields, name)
+		}
+	}
+
+	var pc pipelineConfig
+	if err := toml.UnmarshalTable(tbl, &pc); err != nil {
+		return err
+	}
+
+	if pc.Name == "" {
+		return fmt.Errorf("pipeline: missing name")
+	}
+	for _, p := range c.Pipelines {
+		if p.Name == pc.Name {
+			return fmt.Errorf("pipeline %q: defined more than once", pc.Name)
+		}
+	}
+
+	p := c.DefaultPipeline()
+	p.Name = pc.Name
+	p.Inputs = nil
+	p.Outputs = nil
+
+	p.Tags = make(map[string]string, len(c.Tags))
+	for k, v := range c.Tags {
+		p.Tags[k] = v
+	}
+	if subTable, ok := subTables["tags"]; ok {
+		if err := toml.UnmarshalTable(subTable, p.Tags); err != nil {
+			return fmt.Errorf("pipeline %q: could not parse tags: %v", p.Name, err)
+		}
+	}
+
+	if pc.FlushInterval != nil {
+		p.FlushInterval = pc.FlushInterval.Duration
+	}
+	if pc.FlushJitter != nil {
+		p.FlushJitter = pc.FlushJitter.Duration
+	}
+	if pc.MetricBatchSize != nil {
+		p.MetricBatchSize = *pc.MetricBatchSize
+	}
+	if pc.MetricBufferLimit != nil {
+		p.MetricBufferLimit = *pc.MetricBufferLimit
+	}
+
+	if p.FlushInterval <= 0 {
+		return fmt.Errorf("pipeline %q: flush_interval must be positive; found %s",
+			p.Name, p.FlushInterval)
+	}
+
+	if subTable, ok := subTables["inputs"]; ok {
+		err := eachPlugin(subTable, func(name string, t *ast.Table) error {
+			rp, err := c.newInput(name, t, p.Name, p.Tags)
+			if err != nil {
+				return err
+			}
+			p.Inputs = append(p.Inputs, rp)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("pipeline %q: %v", p.Name, err)
+		}
+	}
+
+	if subTable, ok := subTables["outputs"]; ok {
+		err := eachPlugin(subTable, func(name string, t *ast.Table) error {
+			ro, err := newOutput(name, t, p.MetricBatchSize, p.MetricBufferLimit)
+			if err != nil {
+				return err
+			}
+			p.Outputs = append(p.Outputs, ro)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("pipeline %q: %v", p.Name, err)
+		}
+	}
+
+	if len(p.Outputs) == 0 {
+		return fmt.Errorf("pipeline %q: no outputs found", p.Name)
+	}
+
+	c.Pipelines = append(c.Pipelines, p)
+	return nil
+}
+
+// eachPlugin calls fn for each [[kind.name]] table of an inputs, outputs,
+// processors or aggregators table, in the order the tables are declared.
+func eachPlugin(tbl *ast.Table, fn func(name string, table *ast.Table) error) error {
+	type entry struct {
+		name  string
+		table *ast.Table
+	}
+
+	names := make([]string, 0, len(tbl.Fields))
+	for pluginName := range tbl.Fields {
+		names = append(names, pluginName)
+	}
+	sort.Strings(names)
+
+	var entries []entry
+	for _, pluginName := range names {
+		pluginTables, ok := tbl.Fields[pluginName].([]*ast.Table)
+		if !ok {
+			return fmt.Errorf("Unsupported config format: %s", pluginName)
+		}
+
+		for _, t := range pluginTables {
+			entries = append(entries, entry{name: pluginName, table: t})
+		}
+	}
+
+	// The order matters for processors, which run in declaration order
+	// unless they set an order.
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].table.Line < entries[j].table.Line
+	})
+
+	for _, e := range entries {
+		if err := fn(e.name, e.table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
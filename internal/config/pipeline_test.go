@@ -0,0 +1,97 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal/models"
+	_ "github.com/geekflow/straw/plugins/inputs/all"
+	_ "github.com/geekflow/straw/plugins/outputs/all"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigPipelines(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/pipelines.toml"))
+
+	pipelines := c.AllPipelines()
+	require.Len(t, pipelines, 2)
+
+	def := pipelines[0]
+	require.Equal(t, "", def.Name)
+	require.Equal(t, 10*time.Second, def.FlushInterval)
+	require.Len(t, def.Inputs, 1)
+	require.Equal(t, "mem", def.Inputs[0].Config.Name)
+	require.Len(t, def.Outputs, 1)
+
+	security := pipelines[1]
+	require.Equal(t, "security", security.Name)
+	require.Equal(t, 30*time.Second, security.FlushInterval)
+	require.Equal(t, map[string]string{"dc": "us-east-1", "team": "security"}, security.Tags)
+	require.Len(t, security.Inputs, 1)
+	require.Equal(t, "process", security.Inputs[0].Config.Name)
+	require.Len(t, security.Outputs, 1)
+	require.Equal(t, 50, security.Outputs[0].MetricBatchSize)
+
+	// Global tags are not changed by the pipeline tags.
+	require.Equal(t, map[string]string{"dc": "us-east-1"}, c.Tags)
+
+	// The pipeline tags are added to the metrics of its inputs only.
+	m := security.Inputs[0].MakeMetric(testutil.MustMetric("processes", nil,
+		map[string]interface{}{"total": int64(1)}, time.Unix(0, 0)))
+	require.Equal(t, map[string]string{"dc": "us-east-1", "team": "security"}, m.Tags())
+	m = def.Inputs[0].MakeMetric(testutil.MustMetric("mem", nil,
+		map[string]interface{}{"used": int64(1)}, time.Unix(0, 0)))
+	require.Equal(t, map[string]string{"dc": "us-east-1"}, m.Tags())
+}
+
+func TestLoadConfigInputInstances(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/instances.toml"))
+
+	pipelines := c.AllPipelines()
+	require.Len(t, pipelines, 2)
+	inputs := pipelineInputs(pipelines)
+	require.Len(t, inputs, 4)
+
+	var configs [][]interface{}
+	for _, input := range inputs {
+		configs = append(configs, []interface{}{
+			input.Config.Alias, input.Config.Pipeline, input.Config.Instance})
+	}
+	require.Equal(t, [][]interface{}{
+		{"", "", 1},
+		{"", "", 2},
+		{"other", "", 1},
+		{"", "security", 1},
+	}, configs)
+
+	// Each input counts its own drops, the counters are kept when the
+	// config is loaded again.
+	dropped := make([]int64, len(inputs))
+	for i, input := range inputs {
+		dropped[i] = input.MetricsDropped()
+	}
+	inputs[1].MetricDropped(testutil.MustMetric("mem", nil,
+		map[string]interface{}{"used": int64(1)}, time.Unix(0, 0)))
+
+	reloaded := NewConfig()
+	require.NoError(t, reloaded.LoadConfig("./testdata/instances.toml"))
+	for i, input := range pipelineInputs(reloaded.AllPipelines()) {
+		want := dropped[i]
+		if i == 1 {
+			want++
+		}
+		require.Equal(t, want, input.MetricsDropped())
+	}
+}
+
+// pipelineInputs returns the inputs of the pipelines in order.
+func pipelineInputs(pipelines []*Pipeline) []*models.RunningInput {
+	var inputs []*models.RunningInput
+	for _, p := range pipelines {
+		inputs = append(inputs, p.Inputs...)
+	}
+	return inputs
+}
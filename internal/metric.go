@@ -28,12 +28,19 @@ type Field struct {
 type Metric interface {
 	Name() string
 	Tags() map[string]string
+	// TagList returns the tags sorted by key.  The list may be shared with
+	// copies of the metric and must not be modified.
 	TagList() []*Tag
 	Fields() map[string]interface{}
+	// FieldList returns the fields.  The list may be shared with copies of
+	// the metric and must not be modified.
 	FieldList() []*Field
 	Time() time.Time
 	Type() ValueType
 
+	// SetTime sets the timestamp of the metric.
+	SetTime(t time.Time)
+
 	// Name functions
 	SetName(name string)
 	AddPrefix(prefix string)
@@ -45,7 +52,18 @@ type Metric interface {
 	AddTag(key, value string)
 	RemoveTag(key string)
 
-	// Copy returns a deep copy of the Metric.
+	// Field functions
+	GetField(key string) (interface{}, bool)
+	HasField(key string) bool
+	AddField(key string, value interface{})
+	RemoveField(key string)
+
+	// HashID returns an identifier of the series of the metric, computed
+	// from the name and the sorted tags.
+	HashID() uint64
+
+	// Copy returns a copy of the Metric.  Tags and fields are shared with the
+	// original until one of them is modified.
 	Copy() Metric
 
 	// Accept marks the metric as processed successfully and written to an output.
@@ -1,10 +1,41 @@
 package models
 
 import (
+	"fmt"
 	"github.com/geekflow/straw/internal"
 	"sync"
 )
 
+// batchPool holds the slices returned by Batch for reuse once the batch has
+// been accepted or rejected.
+var batchPool = sync.Pool{
+	New: func() interface{} {
+		return new([]internal.Metric)
+	},
+}
+
+// BufferOrder is the order in which metrics are taken from a Buffer.
+type BufferOrder int
+
+const (
+	// BufferOrderLIFO batches the most recently added metrics first.
+	BufferOrderLIFO BufferOrder = iota
+	// BufferOrderFIFO batches the oldest metrics first.
+	BufferOrderFIFO
+)
+
+// ParseBufferOrder returns the BufferOrder with the given name.
+func ParseBufferOrder(name string) (BufferOrder, error) {
+	switch name {
+	case "", "lifo":
+		return BufferOrderLIFO, nil
+	case "fifo":
+		return BufferOrderFIFO, nil
+	default:
+		return BufferOrderLIFO, fmt.Errorf("invalid buffer_order %q", name)
+	}
+}
+
 // Buffer stores metrics in a circular buffer.
 type Buffer struct {
 	sync.Mutex
@@ -14,18 +45,32 @@ type Buffer struct {
 	size  int // number of metrics currently in the buffer
 	cap   int // the capacity of the buffer
 
+	order   BufferOrder
+	bytes   int64 // estimated size of the metrics currently in the buffer
+	byteCap int64 // the capacity of the buffer in bytes, 0 is unlimited
+
 	batchFirst int // index of the first metric in the batch
 	batchSize  int // number of metrics currently in the batch
 }
 
-// NewBuffer returns a new empty Buffer with the given capacity.
-func NewBuffer(name string, alias string, capacity int) *Buffer {
+// NewBuffer returns a new empty Buffer with the given capacity.  If byteCap
+// is not zero, the oldest metrics are dropped once the estimated size of the
+// buffered metrics exceeds it.
+func NewBuffer(
+	name string,
+	alias string,
+	capacity int,
+	byteCap int64,
+	order BufferOrder,
+) *Buffer {
 	b := &Buffer{
-		buf:   make([]internal.Metric, capacity),
-		first: 0,
-		last:  0,
-		size:  0,
-		cap:   capacity,
+		buf:     make([]internal.Metric, capacity),
+		first:   0,
+		last:    0,
+		size:    0,
+		cap:     capacity,
+		order:   order,
+		byteCap: byteCap,
 	}
 	return b
 }
@@ -53,14 +98,24 @@ func (b *Buffer) metricDropped(metric internal.Metric) {
 	metric.Reject()
 }
 
+// metricBytes returns the estimated size of the metric when byte limits are
+// in use, otherwise it returns 0.
+func (b *Buffer) metricBytes(m internal.Metric) int64 {
+	if b.byteCap == 0 {
+		return 0
+	}
+	return MetricSize(m)
+}
+
 func (b *Buffer) add(m internal.Metric) int {
 	dropped := 0
 	// Check if Buffer is full
 	if b.size == b.cap {
+		b.bytes -= b.metricBytes(b.buf[b.last])
 		b.metricDropped(b.buf[b.last])
 		dropped++
 
-		if b.last == b.batchFirst && b.batchSize > 0 {
+		if b.order == BufferOrderLIFO && b.last == b.batchFirst && b.batchSize > 0 {
 			b.batchSize--
 			b.batchFirst = b.next(b.batchFirst)
 		}
@@ -70,12 +125,30 @@ func (b *Buffer) add(m internal.Metric) int {
 
 	b.buf[b.last] = m
 	b.last = b.next(b.last)
+	b.bytes += b.metricBytes(m)
 
 	if b.size == b.cap {
 		b.first = b.next(b.first)
 	}
 
 	b.size = min(b.size+1, b.cap)
+	return dropped + b.trimBytes()
+}
+
+// trimBytes drops the oldest metrics until the buffer is within its byte
+// capacity, always keeping the newest metric.  Returns the number of metrics
+// dropped.
+func (b *Buffer) trimBytes() int {
+	dropped := 0
+	for b.byteCap > 0 && b.bytes > b.byteCap && b.size > 1 {
+		m := b.buf[b.first]
+		b.bytes -= b.metricBytes(m)
+		b.metricDropped(m)
+		b.buf[b.first] = nil
+		b.first = b.next(b.first)
+		b.size--
+		dropped++
+	}
 	return dropped
 }
 
@@ -94,16 +167,33 @@ func (b *Buffer) Add(metrics ...internal.Metric) int {
 	return dropped
 }
 
-// Batch returns a slice containing up to batchSize of the most recently added
-// metrics.  Metrics are ordered from newest to oldest in the batch.  The
-// batch must not be modified by the client.
-func (b *Buffer) Batch(batchSize int) []internal.Metric {
+// Batch returns a slice containing up to batchSize metrics and, if
+// batchBytes is not zero, at most batchBytes of estimated size; at least one
+// metric is returned if the buffer is not empty.  With BufferOrderLIFO the
+// batch holds the most recently added metrics ordered from newest to oldest,
+// with BufferOrderFIFO it holds the oldest metrics ordered from oldest to
+// newest.  The batch must not be modified by the client and is only valid
+// until it is passed to Accept or Reject.
+func (b *Buffer) Batch(batchSize int, batchBytes int64) []internal.Metric {
 	b.Lock()
 	defer b.Unlock()
 
-	outLen := min(b.size, batchSize)
-	out := make([]internal.Metric, outLen)
+	outLen := b.batchLen(batchSize, batchBytes)
 	if outLen == 0 {
+		return []internal.Metric{}
+	}
+	out := getBatch(outLen)
+
+	if b.order == BufferOrderFIFO {
+		for i := range out {
+			out[i] = b.buf[b.first]
+			b.bytes -= b.metricBytes(out[i])
+			b.buf[b.first] = nil
+			b.first = b.next(b.first)
+		}
+
+		b.batchSize = outLen
+		b.size -= outLen
 		return out
 	}
 
@@ -114,6 +204,7 @@ func (b *Buffer) Batch(batchSize int) []internal.Metric {
 	batchIndex := b.batchFirst
 	for i := range out {
 		out[len(out)-1-i] = b.buf[batchIndex]
+		b.bytes -= b.metricBytes(b.buf[batchIndex])
 		b.buf[batchIndex] = nil
 		batchIndex = b.next(batchIndex)
 	}
@@ -123,6 +214,34 @@ func (b *Buffer) Batch(batchSize int) []internal.Metric {
 	return out
 }
 
+// batchLen returns the number of metrics to take for the next batch.
+func (b *Buffer) batchLen(batchSize int, batchBytes int64) int {
+	outLen := min(b.size, batchSize)
+	if batchBytes == 0 || outLen == 0 {
+		return outLen
+	}
+
+	index := b.first
+	if b.order == BufferOrderLIFO {
+		index = b.prev(b.last)
+	}
+
+	var bytes int64
+	for n := 0; n < outLen; n++ {
+		bytes += MetricSize(b.buf[index])
+		if bytes > batchBytes && n > 0 {
+			return n
+		}
+
+		if b.order == BufferOrderLIFO {
+			index = b.prev(index)
+		} else {
+			index = b.next(index)
+		}
+	}
+	return outLen
+}
+
 // Accept marks the batch, acquired from Batch(), as successfully written.
 func (b *Buffer) Accept(batch []internal.Metric) {
 	b.Lock()
@@ -133,18 +252,25 @@ func (b *Buffer) Accept(batch []internal.Metric) {
 	}
 
 	b.resetBatch()
+	putBatch(batch)
 }
 
 // Reject returns the batch, acquired from Batch(), to the buffer and marks it
-// as unsent.
-func (b *Buffer) Reject(batch []internal.Metric) {
+// as unsent.  Returns the number of metrics dropped to make room.
+func (b *Buffer) Reject(batch []internal.Metric) int {
 	b.Lock()
 	defer b.Unlock()
 
 	if len(batch) == 0 {
-		return
+		return 0
 	}
 
+	if b.order == BufferOrderFIFO {
+		return b.rejectFIFO(batch)
+	}
+
+	dropped := 0
+
 	older := b.dist(b.first, b.batchFirst)
 	free := b.cap - b.size
 	restore := min(len(batch), free+older)
@@ -160,8 +286,10 @@ func (b *Buffer) Reject(batch []internal.Metric) {
 		re = b.prev(re)
 
 		if b.buf[re] != nil {
+			b.bytes -= b.metricBytes(b.buf[re])
 			b.metricDropped(b.buf[re])
 			b.first = b.next(b.first)
+			dropped++
 		}
 
 		b.buf[re] = b.buf[rp]
@@ -174,13 +302,42 @@ func (b *Buffer) Reject(batch []internal.Metric) {
 		if i < restore {
 			re = b.prev(re)
 			b.buf[re] = batch[i]
+			b.bytes += b.metricBytes(batch[i])
 			b.size = min(b.size+1, b.cap)
 		} else {
 			b.metricDropped(batch[i])
+			dropped++
 		}
 	}
 
+	dropped += b.trimBytes()
 	b.resetBatch()
+	putBatch(batch)
+	return dropped
+}
+
+// rejectFIFO returns a batch taken from the front of the buffer to the front
+// of the buffer.  If there is not enough room the oldest metrics of the batch
+// are dropped.  Returns the number of metrics dropped.
+func (b *Buffer) rejectFIFO(batch []internal.Metric) int {
+	dropped := 0
+	for i := len(batch) - 1; i >= 0; i-- {
+		if b.size == b.cap {
+			b.metricDropped(batch[i])
+			dropped++
+			continue
+		}
+
+		b.first = b.prev(b.first)
+		b.buf[b.first] = batch[i]
+		b.bytes += b.metricBytes(batch[i])
+		b.size++
+	}
+
+	dropped += b.trimBytes()
+	b.resetBatch()
+	putBatch(batch)
+	return dropped
 }
 
 // dist returns the distance between two indexes.  Because this data structure
@@ -224,6 +381,54 @@ func (b *Buffer) resetBatch() {
 	b.batchSize = 0
 }
 
+// MetricSize returns an estimate of the size of the metric in bytes when
+// serialized as line protocol.
+func MetricSize(m internal.Metric) int64 {
+	// Name, separators, a 19 digit timestamp and the newline.
+	size := len(m.Name()) + 2 + 19 + 1
+
+	for _, tag := range m.TagList() {
+		size += len(tag.Key) + len(tag.Value) + 2
+	}
+
+	for _, field := range m.FieldList() {
+		size += len(field.Key) + 2
+		switch v := field.Value.(type) {
+		case string:
+			size += len(v) + 2
+		case bool:
+			size += 5
+		default:
+			// Largest int64, uint64 and float64 representations.
+			size += 24
+		}
+	}
+	return int64(size)
+}
+
+// getBatch returns a slice of length n, reusing a pooled slice if possible.
+func getBatch(n int) []internal.Metric {
+	p := batchPool.Get().(*[]internal.Metric)
+	if cap(*p) < n {
+		batchPool.Put(p)
+		return make([]internal.Metric, n)
+	}
+	return (*p)[:n]
+}
+
+// putBatch returns a batch to the pool.  The batch must not be used after
+// calling this function.
+func putBatch(batch []internal.Metric) {
+	if cap(batch) == 0 {
+		return
+	}
+	for i := range batch {
+		batch[i] = nil
+	}
+	batch = batch[:0]
+	batchPool.Put(&batch)
+}
+
 func min(a, b int) int {
 	if b < a {
 		return b
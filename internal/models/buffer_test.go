@@ -0,0 +1,151 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBuffer(capacity int, byteCap int64, order BufferOrder) *Buffer {
+	return NewBuffer("test", "", capacity, byteCap, order)
+}
+
+func metricN(n int) internal.Metric {
+	return testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{"value": int64(n)},
+		time.Unix(int64(n), 0))
+}
+
+func values(batch []internal.Metric) []int64 {
+	out := make([]int64, 0, len(batch))
+	for _, m := range batch {
+		out = append(out, m.Fields()["value"].(int64))
+	}
+	return out
+}
+
+func TestBufferBatchLIFO(t *testing.T) {
+	b := newTestBuffer(5, 0, BufferOrderLIFO)
+	b.Add(metricN(1), metricN(2), metricN(3))
+
+	batch := b.Batch(2, 0)
+	require.Equal(t, []int64{3, 2}, values(batch))
+	b.Accept(batch)
+
+	batch = b.Batch(2, 0)
+	require.Equal(t, []int64{1}, values(batch))
+	b.Accept(batch)
+	require.Equal(t, 0, b.Len())
+}
+
+func TestBufferBatchFIFO(t *testing.T) {
+	b := newTestBuffer(5, 0, BufferOrderFIFO)
+	b.Add(metricN(1), metricN(2), metricN(3))
+
+	batch := b.Batch(2, 0)
+	require.Equal(t, []int64{1, 2}, values(batch))
+	b.Accept(batch)
+
+	batch = b.Batch(2, 0)
+	require.Equal(t, []int64{3}, values(batch))
+	b.Accept(batch)
+	require.Equal(t, 0, b.Len())
+}
+
+func TestBufferRejectFIFO(t *testing.T) {
+	b := newTestBuffer(5, 0, BufferOrderFIFO)
+	b.Add(metricN(1), metricN(2), metricN(3))
+
+	batch := b.Batch(2, 0)
+	b.Add(metricN(4))
+	require.Equal(t, 0, b.Reject(batch))
+	require.Equal(t, 4, b.Len())
+
+	batch = b.Batch(5, 0)
+	require.Equal(t, []int64{1, 2, 3, 4}, values(batch))
+}
+
+func TestBufferRejectFIFODropsOldest(t *testing.T) {
+	b := newTestBuffer(3, 0, BufferOrderFIFO)
+	b.Add(metricN(1), metricN(2), metricN(3))
+
+	batch := b.Batch(2, 0)
+	b.Add(metricN(4), metricN(5))
+	require.Equal(t, 2, b.Reject(batch))
+	require.Equal(t, 3, b.Len())
+
+	batch = b.Batch(3, 0)
+	require.Equal(t, []int64{3, 4, 5}, values(batch))
+}
+
+func TestBufferFullFIFODropsOldest(t *testing.T) {
+	b := newTestBuffer(3, 0, BufferOrderFIFO)
+	dropped := b.Add(metricN(1), metricN(2), metricN(3), metricN(4))
+	require.Equal(t, 1, dropped)
+
+	batch := b.Batch(3, 0)
+	require.Equal(t, []int64{2, 3, 4}, values(batch))
+}
+
+func TestBufferByteLimitDropsOldest(t *testing.T) {
+	size := MetricSize(metricN(1))
+
+	for _, order := range []BufferOrder{BufferOrderLIFO, BufferOrderFIFO} {
+		b := newTestBuffer(10, 2*size, order)
+		dropped := b.Add(metricN(1), metricN(2), metricN(3))
+		require.Equal(t, 1, dropped)
+		require.Equal(t, 2, b.Len())
+
+		batch := b.Batch(10, 0)
+		require.ElementsMatch(t, []int64{2, 3}, values(batch))
+	}
+}
+
+func TestBufferBatchBytes(t *testing.T) {
+	size := MetricSize(metricN(1))
+
+	b := newTestBuffer(10, 0, BufferOrderFIFO)
+	b.Add(metricN(1), metricN(2), metricN(3))
+
+	batch := b.Batch(10, 2*size)
+	require.Equal(t, []int64{1, 2}, values(batch))
+	b.Accept(batch)
+
+	// At least one metric is returned even if it is larger than the limit.
+	batch = b.Batch(10, 1)
+	require.Equal(t, []int64{3}, values(batch))
+}
+
+func TestBufferRejectByteLimit(t *testing.T) {
+	size := MetricSize(metricN(1))
+
+	b := newTestBuffer(10, 2*size, BufferOrderLIFO)
+	b.Add(metricN(1), metricN(2))
+
+	batch := b.Batch(2, 0)
+	b.Add(metricN(3))
+	require.Equal(t, 1, b.Reject(batch))
+	require.Equal(t, 2, b.Len())
+
+	batch = b.Batch(10, 0)
+	require.Equal(t, []int64{3, 2}, values(batch))
+}
+
+func TestBufferRejectFIFOByteLimit(t *testing.T) {
+	size := MetricSize(metricN(1))
+
+	b := newTestBuffer(10, 2*size, BufferOrderFIFO)
+	b.Add(metricN(1), metricN(2))
+
+	batch := b.Batch(2, 0)
+	b.Add(metricN(3))
+	require.Equal(t, 1, b.Reject(batch))
+	require.Equal(t, 2, b.Len())
+
+	batch = b.Batch(10, 0)
+	require.Equal(t, []int64{2, 3}, values(batch))
+}
@@ -25,5 +25,18 @@ func makemetric(
 		metric.AddSuffix(nameSuffix)
 	}
 
+	// Apply plugin-wide tags, then global tags; tags set by the plugin
+	// take precedence.
+	for k, v := range tags {
+		if !metric.HasTag(k) {
+			metric.AddTag(k, v)
+		}
+	}
+	for k, v := range globalTags {
+		if !metric.HasTag(k) {
+			metric.AddTag(k, v)
+		}
+	}
+
 	return metric
 }
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeMetricTags(t *testing.T) {
+	tests := []struct {
+		name       string
+		tags       map[string]string
+		pluginTags map[string]string
+		globalTags map[string]string
+		expected   map[string]string
+	}{
+		{
+			name:     "no tags",
+			tags:     map[string]string{"cpu": "cpu0"},
+			expected: map[string]string{"cpu": "cpu0"},
+		},
+		{
+			name:       "global tags",
+			tags:       map[string]string{"cpu": "cpu0"},
+			globalTags: map[string]string{"host": "web01", "dc": "us-east-1"},
+			expected:   map[string]string{"cpu": "cpu0", "host": "web01", "dc": "us-east-1"},
+		},
+		{
+			name:       "plugin tags override global tags",
+			tags:       map[string]string{"cpu": "cpu0"},
+			pluginTags: map[string]string{"role": "web"},
+			globalTags: map[string]string{"role": "global", "host": "web01"},
+			expected:   map[string]string{"cpu": "cpu0", "role": "web", "host": "web01"},
+		},
+		{
+			name:       "metric tags override plugin and global tags",
+			tags:       map[string]string{"cpu": "cpu0", "host": "container01"},
+			pluginTags: map[string]string{"cpu": "plugin"},
+			globalTags: map[string]string{"host": "web01"},
+			expected:   map[string]string{"cpu": "cpu0", "host": "container01"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ri := NewRunningInput(nil, &InputConfig{
+				Name: "cpu",
+				Tags: tt.pluginTags,
+			})
+			ri.SetDefaultTags(tt.globalTags)
+
+			m := ri.MakeMetric(testutil.MustMetric("cpu",
+				tt.tags,
+				map[string]interface{}{"usage_idle": 99.0},
+				time.Unix(0, 0)))
+			require.Equal(t, tt.expected, m.Tags())
+		})
+	}
+}
+
+func TestMakeMetricTagsNotShared(t *testing.T) {
+	globalTags := map[string]string{"host": "web01"}
+	ri := NewRunningInput(nil, &InputConfig{Name: "cpu"})
+	ri.SetDefaultTags(globalTags)
+
+	m := ri.MakeMetric(testutil.MustMetric("cpu", nil,
+		map[string]interface{}{"usage_idle": 99.0}, time.Unix(0, 0)))
+	m.AddTag("host", "changed")
+
+	require.Equal(t, map[string]string{"host": "web01"}, globalTags)
+}
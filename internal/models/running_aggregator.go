@@ -0,0 +1,148 @@
+package models
+
+import (
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/plugins"
+	"sync"
+	"time"
+)
+
+// AggregatorConfig is the common config for all aggregators.
+type AggregatorConfig struct {
+	Name  string
+	Alias string
+	// Period is the length of the periods over which metrics are aggregated.
+	Period time.Duration
+	// Delay is how long after the end of a period its results are pushed,
+	// to include metrics that arrive late.
+	Delay time.Duration
+	// DropOriginal removes the metrics passed to the aggregator instead of
+	// passing them on.
+	DropOriginal bool
+}
+
+// RunningAggregator wraps an aggregator attached to an input or an output.
+type RunningAggregator struct {
+	Aggregator plugins.Aggregator
+	Config     *AggregatorConfig
+
+	sync.Mutex
+	periodStart time.Time
+	periodEnd   time.Time
+	// pending are the metrics of the next period received before the
+	// current one is pushed.
+	pending []internal.Metric
+}
+
+func NewRunningAggregator(aggregator plugins.Aggregator, config *AggregatorConfig) *RunningAggregator {
+	return &RunningAggregator{
+		Aggregator: aggregator,
+		Config:     config,
+	}
+}
+
+func (ra *RunningAggregator) LogName() string {
+	return logName("aggregators", ra.Config.Name, ra.Config.Alias)
+}
+
+// Init runs the Init function of the aggregator, if it has one.
+func (ra *RunningAggregator) Init() error {
+	if a, ok := ra.Aggregator.(interface{ Init() error }); ok {
+		return a.Init()
+	}
+	return nil
+}
+
+// Start sets the current period to the one containing now, aligned to a
+// multiple of the period.
+func (ra *RunningAggregator) Start(now time.Time) {
+	ra.Lock()
+	defer ra.Unlock()
+	ra.periodStart = now.Truncate(ra.Config.Period)
+	ra.periodEnd = ra.periodStart.Add(ra.Config.Period)
+}
+
+// PeriodEnd returns the end of the current period.
+func (ra *RunningAggregator) PeriodEnd() time.Time {
+	ra.Lock()
+	defer ra.Unlock()
+	return ra.periodEnd
+}
+
+// Add passes the metric to the aggregator and reports whether the original
+// should be dropped.  Metrics older than the current period are ignored,
+// metrics of the next period are kept until the current one is pushed.
+func (ra *RunningAggregator) Add(m internal.Metric) bool {
+	ra.Lock()
+	defer ra.Unlock()
+
+	switch t := m.Time(); {
+	case t.Before(ra.periodStart):
+	case t.Before(ra.periodEnd):
+		ra.Aggregator.Add(m)
+	case t.Before(ra.periodEnd.Add(ra.Config.Period)):
+		ra.pending = append(ra.pending, m.Copy())
+	}
+	return ra.Config.DropOriginal
+}
+
+// Push returns the metrics aggregated over the current period and starts the
+// next one.
+func (ra *RunningAggregator) Push() []internal.Metric {
+	ra.Lock()
+	defer ra.Unlock()
+
+	metrics := ra.Aggregator.Push()
+	for _, m := range metrics {
+		if m.Time().IsZero() {
+			m.SetTime(ra.periodEnd)
+		}
+	}
+	ra.Aggregator.Reset()
+
+	ra.periodStart = ra.periodEnd
+	ra.periodEnd = ra.periodEnd.Add(ra.Config.Period)
+	pending := ra.pending
+	ra.pending = nil
+	for _, m := range pending {
+		ra.Aggregator.Add(m)
+	}
+	return metrics
+}
+
+// Flush pushes the current period and, if the aggregator keeps state across
+// periods, the metrics it still holds.  It is called once when the agent
+// stops, so that no held metric is lost.
+func (ra *RunningAggregator) Flush() []internal.Metric {
+	metrics := ra.Push()
+
+	a, ok := ra.Aggregator.(interface{ Flush() []internal.Metric })
+	if !ok {
+		return metrics
+	}
+
+	ra.Lock()
+	defer ra.Unlock()
+	for _, m := range a.Flush() {
+		if m.Time().IsZero() {
+			m.SetTime(ra.periodStart)
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+// RunningAggregators are the aggregators of an input or an output.
+type RunningAggregators []*RunningAggregator
+
+// Add passes the metric to each aggregator and reports whether the original
+// should be dropped.
+func (ras RunningAggregators) Add(m internal.Metric) bool {
+	drop := false
+	for _, ra := range ras {
+		if ra.Add(m) {
+			drop = true
+		}
+	}
+	return drop
+}
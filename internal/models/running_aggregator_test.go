@@ -0,0 +1,115 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/metric"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// countAggregator counts the metrics added each period.
+type countAggregator struct {
+	count int64
+}
+
+func (*countAggregator) SampleConfig() string  { return "" }
+func (*countAggregator) Description() string   { return "" }
+func (a *countAggregator) Add(internal.Metric) { a.count++ }
+func (a *countAggregator) Reset()              { a.count = 0 }
+
+func (a *countAggregator) Push() []internal.Metric {
+	m, _ := metric.New("count", nil, map[string]interface{}{"count": a.count}, time.Time{})
+	return []internal.Metric{m}
+}
+
+func TestRunningAggregatorPeriods(t *testing.T) {
+	ra := NewRunningAggregator(&countAggregator{},
+		&AggregatorConfig{Name: "count", Period: 10 * time.Second})
+	ra.Start(time.Unix(15, 0))
+	require.Equal(t, time.Unix(20, 0), ra.PeriodEnd())
+
+	add := func(sec int64) {
+		ra.Add(testutil.MustMetric("cpu", nil,
+			map[string]interface{}{"value": 1.0}, time.Unix(sec, 0)))
+	}
+	add(5)  // too old
+	add(10) // current period
+	add(19)
+	add(20) // next period
+	add(30) // too new
+
+	out := ra.Push()
+	require.Len(t, out, 1)
+	require.Equal(t, int64(2), out[0].Fields()["count"])
+	require.Equal(t, time.Unix(20, 0), out[0].Time())
+	require.Equal(t, time.Unix(30, 0), ra.PeriodEnd())
+
+	out = ra.Push()
+	require.Equal(t, int64(1), out[0].Fields()["count"])
+	require.Equal(t, time.Unix(30, 0), out[0].Time())
+}
+
+// holdAggregator holds every metric until it is flushed.
+type holdAggregator struct {
+	held []internal.Metric
+}
+
+func (*holdAggregator) SampleConfig() string       { return "" }
+func (*holdAggregator) Description() string        { return "" }
+func (a *holdAggregator) Add(m internal.Metric)    { a.held = append(a.held, m.Copy()) }
+func (*holdAggregator) Push() []internal.Metric    { return nil }
+func (*holdAggregator) Reset()                     {}
+func (a *holdAggregator) Flush() []internal.Metric { out := a.held; a.held = nil; return out }
+
+func TestRunningAggregatorFlush(t *testing.T) {
+	ra := NewRunningAggregator(&holdAggregator{},
+		&AggregatorConfig{Name: "hold", Period: 10 * time.Second})
+	ra.Start(time.Unix(15, 0))
+
+	ra.Add(testutil.MustMetric("cpu", nil,
+		map[string]interface{}{"value": 1.0}, time.Unix(15, 0)))
+	// Metrics of the next period are flushed too.
+	ra.Add(testutil.MustMetric("cpu", nil,
+		map[string]interface{}{"value": 2.0}, time.Unix(25, 0)))
+
+	out := ra.Flush()
+	require.Len(t, out, 2)
+	require.Equal(t, 1.0, out[0].Fields()["value"])
+	require.Equal(t, 2.0, out[1].Fields()["value"])
+
+	// Aggregators without state across periods only push.
+	count := NewRunningAggregator(&countAggregator{},
+		&AggregatorConfig{Name: "count", Period: 10 * time.Second})
+	count.Start(time.Unix(15, 0))
+	require.Len(t, count.Flush(), 1)
+}
+
+type mockInput struct{}
+
+func (*mockInput) SampleConfig() string               { return "" }
+func (*mockInput) Description() string                { return "" }
+func (*mockInput) Gather(_ plugins.Accumulator) error { return nil }
+
+func TestRunningInputAggregators(t *testing.T) {
+	ri := NewRunningInput(&mockInput{}, &InputConfig{Name: "test"})
+	ri.Aggregators = RunningAggregators{
+		NewRunningAggregator(&countAggregator{},
+			&AggregatorConfig{Name: "a", Period: time.Minute}),
+		NewRunningAggregator(&countAggregator{},
+			&AggregatorConfig{Name: "b", Period: time.Minute, DropOriginal: true}),
+	}
+	for _, ra := range ri.Aggregators {
+		ra.Start(time.Unix(0, 0))
+	}
+
+	out := ri.Process(testutil.MustMetric("cpu", nil,
+		map[string]interface{}{"value": 1.0}, time.Unix(1, 0)))
+	require.Len(t, out, 0)
+	for _, ra := range ri.Aggregators {
+		require.Equal(t, int64(1), ra.Push()[0].Fields()["count"])
+	}
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/plugins"
+	"sort"
+	"sync"
+)
+
+// ProcessorConfig is the common config for all processors.
+type ProcessorConfig struct {
+	Name  string
+	Alias string
+	// Order sets the position of the processor in the chain of its input or
+	// output, lowest first.
+	Order int64
+}
+
+// RunningProcessor wraps a processor attached to an input or an output.
+type RunningProcessor struct {
+	Processor plugins.Processor
+	Config    *ProcessorConfig
+
+	// Inputs may add metrics from several goroutines, the processor is only
+	// applied to one batch at a time.
+	sync.Mutex
+}
+
+func NewRunningProcessor(processor plugins.Processor, config *ProcessorConfig) *RunningProcessor {
+	return &RunningProcessor{
+		Processor: processor,
+		Config:    config,
+	}
+}
+
+func (rp *RunningProcessor) LogName() string {
+	return logName("processors", rp.Config.Name, rp.Config.Alias)
+}
+
+// Init runs the Init function of the processor, if it has one.
+func (rp *RunningProcessor) Init() error {
+	if p, ok := rp.Processor.(interface{ Init() error }); ok {
+		return p.Init()
+	}
+	return nil
+}
+
+// Stop runs the Stop function of the processor, if it has one, once the
+// processor is no longer used.
+func (rp *RunningProcessor) Stop() {
+	if p, ok := rp.Processor.(interface{ Stop() }); ok {
+		p.Stop()
+	}
+}
+
+// Apply applies the processor to the metrics.
+func (rp *RunningProcessor) Apply(in ...internal.Metric) []internal.Metric {
+	rp.Lock()
+	defer rp.Unlock()
+	return rp.Processor.Apply(in...)
+}
+
+// RunningProcessors is a chain of processors.
+type RunningProcessors []*RunningProcessor
+
+// Sort orders the chain by the Order of the processors, keeping the
+// configured order of processors with the same Order.
+func (rps RunningProcessors) Sort() {
+	sort.SliceStable(rps, func(i, j int) bool {
+		return rps[i].Config.Order < rps[j].Config.Order
+	})
+}
+
+// Apply runs the metric through each processor of the chain in turn and
+// returns the metrics that come out of the last one.
+func (rps RunningProcessors) Apply(metric internal.Metric) []internal.Metric {
+	metrics := []internal.Metric{metric}
+	for _, rp := range rps {
+		if len(metrics) == 0 {
+			break
+		}
+		metrics = rp.Apply(metrics...)
+	}
+	return metrics
+}
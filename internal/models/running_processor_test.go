@@ -0,0 +1,91 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// suffixProcessor appends its suffix to the name of each metric, and drops
+// metrics named "drop".
+type suffixProcessor struct {
+	suffix string
+}
+
+func (*suffixProcessor) SampleConfig() string { return "" }
+func (*suffixProcessor) Description() string  { return "" }
+
+func (p *suffixProcessor) Apply(in ...internal.Metric) []internal.Metric {
+	out := in[:0]
+	for _, m := range in {
+		if m.Name() == "drop" {
+			m.Drop()
+			continue
+		}
+		m.AddSuffix(p.suffix)
+		out = append(out, m)
+	}
+	return out
+}
+
+func TestRunningProcessorsOrder(t *testing.T) {
+	procs := RunningProcessors{
+		NewRunningProcessor(&suffixProcessor{"_b"}, &ProcessorConfig{Name: "b", Order: 2}),
+		NewRunningProcessor(&suffixProcessor{"_a"}, &ProcessorConfig{Name: "a", Order: 1}),
+		NewRunningProcessor(&suffixProcessor{"_c"}, &ProcessorConfig{Name: "c", Order: 2}),
+	}
+	procs.Sort()
+
+	out := procs.Apply(testutil.MustMetric("cpu", nil,
+		map[string]interface{}{"value": 1.0}, time.Unix(0, 0)))
+	require.Len(t, out, 1)
+	require.Equal(t, "cpu_a_b_c", out[0].Name())
+
+	out = procs.Apply(testutil.MustMetric("drop", nil,
+		map[string]interface{}{"value": 1.0}, time.Unix(0, 0)))
+	require.Len(t, out, 0)
+}
+
+type mockOutput struct{}
+
+func (*mockOutput) Connect() error                  { return nil }
+func (*mockOutput) Close() error                    { return nil }
+func (*mockOutput) Description() string             { return "" }
+func (*mockOutput) SampleConfig() string            { return "" }
+func (*mockOutput) Write(_ []internal.Metric) error { return nil }
+
+func TestRunningOutputProcessors(t *testing.T) {
+	ro := NewRunningOutput("test", &mockOutput{}, &OutputConfig{Name: "test"}, 0, 0)
+	ro.Processors = RunningProcessors{
+		NewRunningProcessor(&suffixProcessor{"_x"}, &ProcessorConfig{Name: "x"}),
+	}
+
+	ro.AddMetric(testutil.MustMetric("cpu", nil,
+		map[string]interface{}{"value": 1.0}, time.Unix(0, 0)))
+	ro.AddMetric(testutil.MustMetric("drop", nil,
+		map[string]interface{}{"value": 1.0}, time.Unix(0, 0)))
+
+	require.Equal(t, 1, ro.buffer.Len())
+	batch := ro.buffer.Batch(10, 0)
+	require.Equal(t, "cpu_x", batch[0].Name())
+}
+
+// stoppingProcessor records the calls to Stop.
+type stoppingProcessor struct {
+	suffixProcessor
+	stopped int
+}
+
+func (p *stoppingProcessor) Stop() { p.stopped++ }
+
+func TestRunningProcessorStop(t *testing.T) {
+	p := &stoppingProcessor{}
+	NewRunningProcessor(p, &ProcessorConfig{Name: "stopping"}).Stop()
+	require.Equal(t, 1, p.stopped)
+
+	// Processors without a Stop function are left alone.
+	NewRunningProcessor(&suffixProcessor{}, &ProcessorConfig{Name: "suffix"}).Stop()
+}
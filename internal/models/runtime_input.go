@@ -1,19 +1,58 @@
 package models
 
 import (
+	"fmt"
 	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/internal/schedule"
+	"github.com/geekflow/straw/internal/selfstat"
 	"github.com/geekflow/straw/plugins"
+	"strconv"
 
 	log "github.com/sirupsen/logrus"
 	"time"
 )
 
+// OverflowPolicy selects what an input does with a new metric when the
+// metric channel is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until there is room in the channel.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the new metric.
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest metric in the channel to make
+	// room for the new one.
+	OverflowDropOldest
+)
+
+// ParseOverflowPolicy returns the OverflowPolicy with the given name.
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	switch name {
+	case "", "block":
+		return OverflowBlock, nil
+	case "drop_newest":
+		return OverflowDropNewest, nil
+	case "drop_oldest":
+		return OverflowDropOldest, nil
+	default:
+		return OverflowBlock, fmt.Errorf("invalid overflow_policy %q", name)
+	}
+}
+
 type RunningInput struct {
 	Input  plugins.Input
 	Config *InputConfig
 
+	// Processors are applied to each metric after MakeMetric.
+	Processors RunningProcessors
+	// Aggregators receive each metric after the processors.
+	Aggregators RunningAggregators
+
 	log         log.Logger
 	defaultTags map[string]string
+
+	metricsDropped *selfstat.Stat
 }
 
 func NewRunningInput(input plugins.Input, config *InputConfig) *RunningInput {
@@ -21,11 +60,18 @@ func NewRunningInput(input plugins.Input, config *InputConfig) *RunningInput {
 	if config.Alias != "" {
 		tags["alias"] = config.Alias
 	}
+	if config.Pipeline != "" {
+		tags["pipeline"] = config.Pipeline
+	}
+	if config.Instance > 1 {
+		tags["instance"] = strconv.Itoa(config.Instance)
+	}
 
 	return &RunningInput{
 		Input:  input,
 		Config: config,
 		// log:    logger,
+		metricsDropped: selfstat.Register("gather", "metrics_dropped", tags),
 	}
 }
 
@@ -35,6 +81,23 @@ type InputConfig struct {
 	Alias    string
 	Interval time.Duration
 
+	// Pipeline is the name of the pipeline of the input, empty for the
+	// default pipeline.  Instance numbers the inputs of a pipeline with the
+	// same name and alias from 1, in the order they are declared.
+	Pipeline string
+	Instance int
+
+	// Schedule, if set, runs the input at the times of a cron expression
+	// instead of every Interval.
+	Schedule schedule.Schedule
+	// RoundInterval, CollectionJitter and CollectionOffset override the
+	// agent settings when set.
+	RoundInterval    *bool
+	CollectionJitter *time.Duration
+	CollectionOffset *time.Duration
+
+	OverflowPolicy OverflowPolicy
+
 	NameOverride      string
 	MeasurementPrefix string
 	MeasurementSuffix string
@@ -54,6 +117,9 @@ func logName(pluginType, name, alias string) string {
 }
 
 func (r *RunningInput) Init() error {
+	if p, ok := r.Input.(interface{ Init() error }); ok {
+		return p.Init()
+	}
 	return nil
 }
 
@@ -73,11 +139,56 @@ func (r *RunningInput) MakeMetric(metric internal.Metric) internal.Metric {
 	return m
 }
 
+// Process applies the input's processors to a metric made by MakeMetric and
+// passes the results to its aggregators.
+func (r *RunningInput) Process(metric internal.Metric) []internal.Metric {
+	metrics := []internal.Metric{metric}
+	if len(r.Processors) > 0 {
+		metrics = r.Processors.Apply(metric)
+	}
+	if len(r.Aggregators) == 0 {
+		return metrics
+	}
+
+	out := metrics[:0]
+	for _, m := range metrics {
+		if r.Aggregators.Add(m) {
+			m.Drop()
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
 func (r *RunningInput) Gather(acc plugins.Accumulator) error {
+	dropped := r.MetricsDropped()
 	err := r.Input.Gather(acc)
+	if n := r.MetricsDropped() - dropped; n > 0 {
+		log.Warnf("[%s] Metric channel full; %d metrics have been dropped", r.LogName(), n)
+	}
 	return err
 }
 
+// OverflowPolicy returns the policy to apply when the metric channel is full.
+func (r *RunningInput) OverflowPolicy() OverflowPolicy {
+	return r.Config.OverflowPolicy
+}
+
+// MetricDropped records a metric made by the input that was discarded by its
+// overflow policy.
+func (r *RunningInput) MetricDropped(metric internal.Metric) {
+	r.metricsDropped.Incr(1)
+	metric.Drop()
+}
+
+// MetricsDropped returns the total number of metrics made by the input that
+// were dropped by its overflow policy.  It is reported as the metrics_dropped
+// field of internal_gather.
+func (r *RunningInput) MetricsDropped() int64 {
+	return r.metricsDropped.Get()
+}
+
 func (r *RunningInput) SetDefaultTags(tags map[string]string) {
 	r.defaultTags = tags
 }
@@ -26,13 +26,33 @@ type OutputConfig struct {
 	FlushJitter       *time.Duration
 	MetricBufferLimit int
 	MetricBatchSize   int
+
+	// BufferOrder is the order in which buffered metrics are written.
+	BufferOrder BufferOrder
+	// MetricBufferLimitBytes limits the estimated size of the buffered
+	// metrics, 0 is unlimited.
+	MetricBufferLimitBytes int64
+	// MetricBatchSizeBytes limits the estimated size of each batch, 0 is
+	// unlimited.
+	MetricBatchSizeBytes int64
+
+	// MaxSeries limits the number of unique series seen within
+	// MaxSeriesWindow, 0 is unlimited.
+	MaxSeries       int
+	MaxSeriesWindow time.Duration
+	MaxSeriesAction SeriesAction
+	// MaxSeriesStripTags are the tags removed by the strip_tags action.
+	MaxSeriesStripTags []string
 }
 
 // RunningOutput contains the output configuration
 type RunningOutput struct {
 	// Must be 64-bit aligned
 	newMetricsCount int64
+	newMetricsBytes int64
 	droppedMetrics  int64
+	droppedSeries   int64
+	strippedSeries  int64
 
 	Output            plugins.Output
 	Config            *OutputConfig
@@ -41,7 +61,13 @@ type RunningOutput struct {
 
 	BatchReady chan time.Time
 
+	// Processors are applied to each metric before it is buffered.
+	Processors RunningProcessors
+	// Aggregators receive each metric after the processors.
+	Aggregators RunningAggregators
+
 	buffer *Buffer
+	series *SeriesGuard
 	//log    logger.Logger
 
 	aggMutex sync.Mutex
@@ -79,7 +105,8 @@ func NewRunningOutput(
 	}
 
 	ro := &RunningOutput{
-		buffer:            NewBuffer(config.Name, config.Alias, bufferLimit),
+		buffer: NewBuffer(config.Name, config.Alias, bufferLimit,
+			config.MetricBufferLimitBytes, config.BufferOrder),
 		BatchReady:        make(chan time.Time, 1),
 		Output:            output,
 		Config:            config,
@@ -88,6 +115,11 @@ func NewRunningOutput(
 		//log: logger,
 	}
 
+	if config.MaxSeries > 0 {
+		ro.series = NewSeriesGuard(config.MaxSeries, config.MaxSeriesWindow,
+			config.MaxSeriesAction, config.MaxSeriesStripTags)
+	}
+
 	return ro
 }
 
@@ -101,6 +133,38 @@ func (r *RunningOutput) Init() error {
 
 // AddMetric adds a metric to the output.
 func (r *RunningOutput) AddMetric(metric internal.Metric) {
+	metrics := []internal.Metric{metric}
+	if len(r.Processors) > 0 {
+		metrics = r.Processors.Apply(metric)
+	}
+
+	for _, m := range metrics {
+		if len(r.Aggregators) > 0 && r.Aggregators.Add(m) {
+			m.Drop()
+			continue
+		}
+		r.addMetric(m)
+	}
+}
+
+// AddAggregate adds a metric pushed by one of the output's aggregators,
+// bypassing its processors and aggregators.
+func (r *RunningOutput) AddAggregate(metric internal.Metric) {
+	r.addMetric(metric)
+}
+
+func (r *RunningOutput) addMetric(metric internal.Metric) {
+	if r.series != nil {
+		keep, stripped := r.series.Apply(metric)
+		if stripped {
+			atomic.AddInt64(&r.strippedSeries, 1)
+		}
+		if !keep {
+			atomic.AddInt64(&r.droppedSeries, 1)
+			metric.Drop()
+			return
+		}
+	}
 
 	if output, ok := r.Output.(plugins.AggregatingOutput); ok {
 		r.aggMutex.Lock()
@@ -114,14 +178,29 @@ func (r *RunningOutput) AddMetric(metric internal.Metric) {
 
 	count := atomic.AddInt64(&r.newMetricsCount, 1)
 	if count == int64(r.MetricBatchSize) {
-		atomic.StoreInt64(&r.newMetricsCount, 0)
-		select {
-		case r.BatchReady <- time.Now():
-		default:
+		r.batchReady()
+		return
+	}
+
+	if r.Config.MetricBatchSizeBytes > 0 {
+		bytes := atomic.AddInt64(&r.newMetricsBytes, MetricSize(metric))
+		if bytes >= r.Config.MetricBatchSizeBytes {
+			r.batchReady()
 		}
 	}
 }
 
+// batchReady resets the new metric counters and signals that a full batch
+// is available.
+func (r *RunningOutput) batchReady() {
+	atomic.StoreInt64(&r.newMetricsCount, 0)
+	atomic.StoreInt64(&r.newMetricsBytes, 0)
+	select {
+	case r.BatchReady <- time.Now():
+	default:
+	}
+}
+
 // Write writes all metrics to the output, stopping when all have been sent on or error.
 func (r *RunningOutput) Write() error {
 	if output, ok := r.Output.(plugins.AggregatingOutput); ok {
@@ -133,20 +212,21 @@ func (r *RunningOutput) Write() error {
 	}
 
 	atomic.StoreInt64(&r.newMetricsCount, 0)
+	atomic.StoreInt64(&r.newMetricsBytes, 0)
 
 	// Only process the metrics in the buffer now.  Metrics added while we are writing will be sent on the next call.
 	nBuffer := r.buffer.Len()
-	nBatches := nBuffer/r.MetricBatchSize + 1
 
-	for i := 0; i < nBatches; i++ {
-		batch := r.buffer.Batch(r.MetricBatchSize)
+	for nBuffer > 0 {
+		batch := r.buffer.Batch(r.MetricBatchSize, r.Config.MetricBatchSizeBytes)
 		if len(batch) == 0 {
 			break
 		}
+		nBuffer -= len(batch)
 
 		err := r.write(batch)
 		if err != nil {
-			r.buffer.Reject(batch)
+			r.reject(batch)
 			return err
 		}
 		r.buffer.Accept(batch)
@@ -156,14 +236,14 @@ func (r *RunningOutput) Write() error {
 
 // WriteBatch writes a single batch of metrics to the output.
 func (r *RunningOutput) WriteBatch() error {
-	batch := r.buffer.Batch(r.MetricBatchSize)
+	batch := r.buffer.Batch(r.MetricBatchSize, r.Config.MetricBatchSizeBytes)
 	if len(batch) == 0 {
 		return nil
 	}
 
 	err := r.write(batch)
 	if err != nil {
-		r.buffer.Reject(batch)
+		r.reject(batch)
 		return err
 	}
 	r.buffer.Accept(batch)
@@ -178,6 +258,13 @@ func (r *RunningOutput) Close() {
 	}
 }
 
+// reject returns a batch that failed to write to the buffer, counting the
+// metrics dropped to make room.
+func (r *RunningOutput) reject(batch []internal.Metric) {
+	dropped := r.buffer.Reject(batch)
+	atomic.AddInt64(&r.droppedMetrics, int64(dropped))
+}
+
 func (r *RunningOutput) write(metrics []internal.Metric) error {
 	dropped := atomic.LoadInt64(&r.droppedMetrics)
 	if dropped > 0 {
@@ -185,6 +272,14 @@ func (r *RunningOutput) write(metrics []internal.Metric) error {
 		atomic.StoreInt64(&r.droppedMetrics, 0)
 	}
 
+	droppedSeries := atomic.SwapInt64(&r.droppedSeries, 0)
+	strippedSeries := atomic.SwapInt64(&r.strippedSeries, 0)
+	if droppedSeries > 0 || strippedSeries > 0 {
+		log.Warnf("[%s] Series limit of %d reached; %d metrics have been dropped, "+
+			"%d metrics had tags removed", r.LogName(), r.Config.MaxSeries,
+			droppedSeries, strippedSeries)
+	}
+
 	start := time.Now()
 	err := r.Output.Write(metrics)
 	elapsed := time.Since(start)
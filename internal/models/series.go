@@ -0,0 +1,161 @@
+package models
+
+import (
+	"container/list"
+	"fmt"
+	"github.com/geekflow/straw/internal"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SeriesAction selects what a SeriesGuard does with a metric of a new series
+// once the series limit has been reached.
+type SeriesAction int
+
+const (
+	// SeriesDrop drops metrics of new series.
+	SeriesDrop SeriesAction = iota
+	// SeriesStripTags removes the configured tags from metrics of new series,
+	// the metric is dropped if it still belongs to a new series.
+	SeriesStripTags
+)
+
+// ParseSeriesAction returns the SeriesAction with the given name.
+func ParseSeriesAction(name string) (SeriesAction, error) {
+	switch name {
+	case "", "drop":
+		return SeriesDrop, nil
+	case "strip_tags":
+		return SeriesStripTags, nil
+	default:
+		return SeriesDrop, fmt.Errorf("invalid max_series_action %q", name)
+	}
+}
+
+// DefaultSeriesWindow is how long a series is remembered after its last
+// metric when no window is configured.
+const DefaultSeriesWindow = time.Hour
+
+// SeriesGuard limits the number of unique series, identified by measurement
+// name and tag set, seen within a rolling window.
+type SeriesGuard struct {
+	sync.Mutex
+
+	limit     int
+	window    time.Duration
+	action    SeriesAction
+	stripTags []string
+
+	series map[string]*list.Element
+	seen   *list.List // series ordered by the last time they were seen
+	now    func() time.Time
+}
+
+type seriesEntry struct {
+	key  string
+	seen time.Time
+}
+
+// NewSeriesGuard returns a SeriesGuard allowing up to limit series.
+func NewSeriesGuard(
+	limit int,
+	window time.Duration,
+	action SeriesAction,
+	stripTags []string,
+) *SeriesGuard {
+	if window <= 0 {
+		window = DefaultSeriesWindow
+	}
+
+	return &SeriesGuard{
+		limit:     limit,
+		window:    window,
+		action:    action,
+		stripTags: stripTags,
+		series:    make(map[string]*list.Element),
+		seen:      list.New(),
+		now:       time.Now,
+	}
+}
+
+// Apply records the series of the metric.  It returns false if the metric
+// must be dropped and reports whether tags were removed from the metric.
+func (g *SeriesGuard) Apply(metric internal.Metric) (keep bool, stripped bool) {
+	g.Lock()
+	defer g.Unlock()
+
+	now := g.now()
+	if g.admit(seriesKey(metric), now) {
+		return true, false
+	}
+
+	if g.action != SeriesStripTags {
+		return false, false
+	}
+
+	for _, key := range g.stripTags {
+		if metric.HasTag(key) {
+			metric.RemoveTag(key)
+			stripped = true
+		}
+	}
+
+	if stripped && g.admit(seriesKey(metric), now) {
+		return true, true
+	}
+	return false, stripped
+}
+
+// Len returns the number of series currently tracked.
+func (g *SeriesGuard) Len() int {
+	g.Lock()
+	defer g.Unlock()
+
+	return len(g.series)
+}
+
+// admit returns true if the series is known or there is room for it.
+func (g *SeriesGuard) admit(key string, now time.Time) bool {
+	if e, ok := g.series[key]; ok {
+		e.Value.(*seriesEntry).seen = now
+		g.seen.MoveToBack(e)
+		return true
+	}
+
+	if len(g.series) >= g.limit {
+		g.expire(now)
+		if len(g.series) >= g.limit {
+			return false
+		}
+	}
+
+	g.series[key] = g.seen.PushBack(&seriesEntry{key: key, seen: now})
+	return true
+}
+
+// expire forgets series not seen within the window.  The series seen least
+// recently are at the front of the list, so only expired series are visited.
+func (g *SeriesGuard) expire(now time.Time) {
+	for e := g.seen.Front(); e != nil; e = g.seen.Front() {
+		entry := e.Value.(*seriesEntry)
+		if now.Sub(entry.seen) <= g.window {
+			return
+		}
+		g.seen.Remove(e)
+		delete(g.series, entry.key)
+	}
+}
+
+// seriesKey returns a key identifying the series of the metric.
+func seriesKey(metric internal.Metric) string {
+	var b strings.Builder
+	b.WriteString(metric.Name())
+	for _, tag := range metric.TagList() {
+		b.WriteByte(0)
+		b.WriteString(tag.Key)
+		b.WriteByte(0)
+		b.WriteString(tag.Value)
+	}
+	return b.String()
+}
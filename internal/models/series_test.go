@@ -0,0 +1,105 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func procMetric(pid string) internal.Metric {
+	return testutil.MustMetric("procstat",
+		map[string]string{"process_name": "nginx", "pid": pid},
+		map[string]interface{}{"cpu_usage": 1.0},
+		time.Unix(0, 0))
+}
+
+func TestSeriesGuardDrop(t *testing.T) {
+	g := NewSeriesGuard(2, time.Hour, SeriesDrop, nil)
+
+	for _, pid := range []string{"1", "2", "1"} {
+		keep, _ := g.Apply(procMetric(pid))
+		require.True(t, keep)
+	}
+
+	keep, stripped := g.Apply(procMetric("3"))
+	require.False(t, keep)
+	require.False(t, stripped)
+	require.Equal(t, 2, g.Len())
+}
+
+func TestSeriesGuardStripTags(t *testing.T) {
+	g := NewSeriesGuard(2, time.Hour, SeriesStripTags, []string{"pid"})
+
+	g.Apply(procMetric("1"))
+
+	m := procMetric("2")
+	keep, stripped := g.Apply(m)
+	require.True(t, keep)
+	require.False(t, stripped)
+
+	// The limit is reached and the series without the pid tag is also new.
+	m = procMetric("3")
+	keep, stripped = g.Apply(m)
+	require.False(t, keep)
+	require.True(t, stripped)
+	require.False(t, m.HasTag("pid"))
+}
+
+func TestSeriesGuardStripTagsKnownSeries(t *testing.T) {
+	g := NewSeriesGuard(2, time.Hour, SeriesStripTags, []string{"pid"})
+
+	m := procMetric("1")
+	m.RemoveTag("pid")
+	g.Apply(m)
+	g.Apply(procMetric("2"))
+
+	m = procMetric("3")
+	keep, stripped := g.Apply(m)
+	require.True(t, keep)
+	require.True(t, stripped)
+	require.Equal(t, map[string]string{"process_name": "nginx"}, m.Tags())
+}
+
+func TestSeriesGuardWindow(t *testing.T) {
+	now := time.Unix(0, 0)
+	g := NewSeriesGuard(1, time.Minute, SeriesDrop, nil)
+	g.now = func() time.Time { return now }
+
+	keep, _ := g.Apply(procMetric("1"))
+	require.True(t, keep)
+	keep, _ = g.Apply(procMetric("2"))
+	require.False(t, keep)
+
+	now = now.Add(2 * time.Minute)
+	keep, _ = g.Apply(procMetric("2"))
+	require.True(t, keep)
+}
+
+func TestSeriesGuardExpireLeastRecentlySeen(t *testing.T) {
+	now := time.Unix(0, 0)
+	g := NewSeriesGuard(2, time.Minute, SeriesDrop, nil)
+	g.now = func() time.Time { return now }
+
+	keep, _ := g.Apply(procMetric("1"))
+	require.True(t, keep)
+	keep, _ = g.Apply(procMetric("2"))
+	require.True(t, keep)
+
+	// Seeing series 1 again keeps it while series 2 expires.
+	now = now.Add(45 * time.Second)
+	keep, _ = g.Apply(procMetric("1"))
+	require.True(t, keep)
+
+	now = now.Add(30 * time.Second)
+	keep, _ = g.Apply(procMetric("3"))
+	require.True(t, keep)
+	require.Equal(t, 2, g.Len())
+
+	keep, _ = g.Apply(procMetric("2"))
+	require.False(t, keep)
+	keep, _ = g.Apply(procMetric("1"))
+	require.True(t, keep)
+}
@@ -0,0 +1,31 @@
+// Package schedule computes the times at which a plugin is run.
+package schedule
+
+import "time"
+
+// Schedule describes a sequence of activation times.
+type Schedule interface {
+	// Next returns the first activation time after t, or the zero time if
+	// there is none.
+	Next(t time.Time) time.Time
+}
+
+// Every returns a Schedule that activates every interval.  If round is set,
+// activations are aligned to multiples of the interval since the Unix epoch,
+// shifted by offset; otherwise they follow the previous activation.
+func Every(interval, offset time.Duration, round bool) Schedule {
+	return &intervalSchedule{interval: interval, offset: offset, round: round}
+}
+
+type intervalSchedule struct {
+	interval time.Duration
+	offset   time.Duration
+	round    bool
+}
+
+func (s *intervalSchedule) Next(t time.Time) time.Time {
+	if !s.round {
+		return t.Add(s.interval)
+	}
+	return t.Add(-s.offset).Truncate(s.interval).Add(s.interval + s.offset)
+}
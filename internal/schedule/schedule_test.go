@@ -0,0 +1,80 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04:05", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestEvery(t *testing.T) {
+	s := Every(10*time.Second, 0, false)
+	require.Equal(t, date("2020-01-01 00:00:13"), s.Next(date("2020-01-01 00:00:03")))
+
+	s = Every(10*time.Second, 0, true)
+	require.Equal(t, date("2020-01-01 00:00:10"), s.Next(date("2020-01-01 00:00:03")))
+	require.Equal(t, date("2020-01-01 00:00:20"), s.Next(date("2020-01-01 00:00:10")))
+
+	s = Every(time.Minute, 5*time.Second, true)
+	require.Equal(t, date("2020-01-01 00:00:05"), s.Next(date("2020-01-01 00:00:03")))
+	require.Equal(t, date("2020-01-01 00:01:05"), s.Next(date("2020-01-01 00:00:05")))
+}
+
+func TestParseCronNext(t *testing.T) {
+	tests := []struct {
+		spec string
+		from string
+		next string
+	}{
+		{"0 */5 * * * *", "2020-01-01 10:03:27", "2020-01-01 10:05:00"},
+		{"0 */5 * * * *", "2020-01-01 10:05:00", "2020-01-01 10:10:00"},
+		{"*/5 * * * *", "2020-01-01 23:59:00", "2020-01-02 00:00:00"},
+		{"30 0 3-5 * * *", "2020-01-01 06:00:00", "2020-01-02 03:00:30"},
+		{"0 0 0 29 feb *", "2021-01-01 00:00:00", "2024-02-29 00:00:00"},
+		{"0 0 12 * * mon-fri", "2020-01-03 13:00:00", "2020-01-06 12:00:00"},
+		{"0 0 12 * * 7", "2020-01-03 13:00:00", "2020-01-05 12:00:00"},
+		// Day of month or day of week when both are restricted.
+		{"0 0 0 15 * sun", "2020-01-06 00:00:00", "2020-01-12 00:00:00"},
+		{"0 0 0 1,15 * *", "2020-01-02 00:00:00", "2020-01-15 00:00:00"},
+		{"@hourly", "2020-01-01 10:03:27", "2020-01-01 11:00:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := ParseCron(tt.spec)
+			require.NoError(t, err)
+			require.Equal(t, date(tt.next), s.Next(date(tt.from)))
+		})
+	}
+}
+
+func TestParseCronNoMatch(t *testing.T) {
+	s, err := ParseCron("0 0 0 30 feb *")
+	require.NoError(t, err)
+	require.True(t, s.Next(date("2020-01-01 00:00:00")).IsZero())
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * * *",
+		"* * 24 * * *",
+		"* * * 0 * *",
+		"* * * * 13 *",
+		"*/0 * * * * *",
+		"5-1 * * * * *",
+		"x * * * * *",
+	} {
+		_, err := ParseCron(spec)
+		require.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,115 @@
+// Package selfstat collects statistics about the agent itself.  The
+// statistics are reported by the internal input.
+package selfstat
+
+import (
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/metric"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stat is a counter registered with Register.
+type Stat struct {
+	// Must be 64-bit aligned
+	value int64
+
+	measurement string
+	field       string
+	tags        map[string]string
+	series      string
+}
+
+// Incr adds v to the counter.
+func (s *Stat) Incr(v int64) {
+	atomic.AddInt64(&s.value, v)
+}
+
+// Get returns the value of the counter.
+func (s *Stat) Get() int64 {
+	return atomic.LoadInt64(&s.value)
+}
+
+var registry = struct {
+	sync.Mutex
+	stats map[string]*Stat
+}{stats: make(map[string]*Stat)}
+
+// Register returns the counter for the field of the measurement with the
+// given tags, creating it on the first call.
+func Register(measurement, field string, tags map[string]string) *Stat {
+	registry.Lock()
+	defer registry.Unlock()
+
+	series := seriesKey(measurement, tags)
+	key := series + "\x00" + field
+	if s, ok := registry.stats[key]; ok {
+		return s
+	}
+
+	s := &Stat{
+		measurement: measurement,
+		field:       field,
+		tags:        make(map[string]string, len(tags)),
+		series:      series,
+	}
+	for k, v := range tags {
+		s.tags[k] = v
+	}
+	registry.stats[key] = s
+	return s
+}
+
+// Metrics returns a metric for each registered measurement and tag set,
+// named "internal_<measurement>" and holding the value of each counter.
+func Metrics() []internal.Metric {
+	registry.Lock()
+	defer registry.Unlock()
+
+	keys := make([]string, 0, len(registry.stats))
+	for key := range registry.stats {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// The counters of a series are adjacent once sorted.
+	now := time.Now()
+	var metrics []internal.Metric
+	for i := 0; i < len(keys); {
+		first := registry.stats[keys[i]]
+		fields := make(map[string]interface{})
+		for ; i < len(keys) && registry.stats[keys[i]].series == first.series; i++ {
+			s := registry.stats[keys[i]]
+			fields[s.field] = s.Get()
+		}
+
+		m, err := metric.New("internal_"+first.measurement, first.tags, fields, now)
+		if err != nil {
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+// seriesKey returns a key identifying the measurement and tag set.
+func seriesKey(measurement string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(measurement)
+	for _, k := range keys {
+		b.WriteByte(0)
+		b.WriteString(k)
+		b.WriteByte(0)
+		b.WriteString(tags[k])
+	}
+	return b.String()
+}
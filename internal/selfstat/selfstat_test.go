@@ -0,0 +1,44 @@
+package selfstat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	tags := map[string]string{"input": "cpu"}
+	dropped := Register("test_register", "metrics_dropped", tags)
+	gathered := Register("test_register", "metrics_gathered", tags)
+	other := Register("test_register", "metrics_dropped", map[string]string{"input": "mem"})
+
+	// The tags are copied.
+	tags["input"] = "changed"
+
+	dropped.Incr(2)
+	Register("test_register", "metrics_dropped", map[string]string{"input": "cpu"}).Incr(1)
+	gathered.Incr(5)
+	other.Incr(7)
+	require.Equal(t, int64(3), dropped.Get())
+
+	var found []map[string]interface{}
+	for _, m := range Metrics() {
+		if m.Name() != "internal_test_register" {
+			continue
+		}
+		found = append(found, map[string]interface{}{
+			"tags":   m.Tags(),
+			"fields": m.Fields(),
+		})
+	}
+	require.Equal(t, []map[string]interface{}{
+		{
+			"tags":   map[string]string{"input": "cpu"},
+			"fields": map[string]interface{}{"metrics_dropped": int64(3), "metrics_gathered": int64(5)},
+		},
+		{
+			"tags":   map[string]string{"input": "mem"},
+			"fields": map[string]interface{}{"metrics_dropped": int64(7)},
+		},
+	}, found)
+}
@@ -9,4 +9,19 @@ Usage:
 The commands & flags are:
 
   version             print the version to stdout
+  record              write the metrics gathered by the inputs to a file
+                        --duration <duration>  stop after the duration
+                        --out <file>           capture file, - for stdout
+  replay <file>       write the metrics of a capture file to the outputs of
+                      the pipelines they were recorded in
+                        --preserve-timing      keep the original spacing of
+                                               the metrics
+
+Examples:
+
+  # record the metrics of the configured inputs for an hour
+  straw --config straw.conf record --duration 1h --out capture.lp
+
+  # send a capture through the configured outputs
+  straw --config straw.conf replay capture.lp
 `
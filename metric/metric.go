@@ -3,7 +3,7 @@ package metric
 import (
 	"fmt"
 	"github.com/geekflow/straw/internal"
-	"sort"
+	"hash/fnv"
 	"time"
 )
 
@@ -15,6 +15,12 @@ type metric struct {
 
 	tp        internal.ValueType
 	aggregate bool
+
+	// tagsShared and fieldsShared are set when the lists may be referenced
+	// by a copy of this metric.  Shared lists are cloned before the first
+	// modification so that copies never observe each other's changes.
+	tagsShared   bool
+	fieldsShared bool
 }
 
 func New(
@@ -40,21 +46,31 @@ func New(
 	}
 
 	if len(tags) > 0 {
-		m.tags = make([]*internal.Tag, 0, len(tags))
+		// Allocate the tags in one block instead of one at a time.
+		tagBuf := make([]internal.Tag, 0, len(tags))
 		for k, v := range tags {
-			m.tags = append(m.tags,
-				&internal.Tag{Key: k, Value: v})
+			tagBuf = append(tagBuf, internal.Tag{Key: k, Value: v})
+		}
+		sortTags(tagBuf)
+
+		m.tags = make([]*internal.Tag, len(tagBuf))
+		for i := range tagBuf {
+			m.tags[i] = &tagBuf[i]
 		}
-		sort.Slice(m.tags, func(i, j int) bool { return m.tags[i].Key < m.tags[j].Key })
 	}
 
-	m.fields = make([]*internal.Field, 0, len(fields))
+	fieldBuf := make([]internal.Field, 0, len(fields))
 	for k, v := range fields {
 		v := convertField(v)
 		if v == nil {
 			continue
 		}
-		m.AddField(k, v)
+		fieldBuf = append(fieldBuf, internal.Field{Key: k, Value: v})
+	}
+
+	m.fields = make([]*internal.Field, len(fieldBuf))
+	for i := range fieldBuf {
+		m.fields[i] = &fieldBuf[i]
 	}
 
 	return m, nil
@@ -123,6 +139,10 @@ func (m *metric) Type() internal.ValueType {
 	return m.tp
 }
 
+func (m *metric) SetTime(t time.Time) {
+	m.tm = t
+}
+
 func (m *metric) SetName(name string) {
 	m.name = name
 }
@@ -136,6 +156,7 @@ func (m *metric) AddSuffix(suffix string) {
 }
 
 func (m *metric) AddTag(key, value string) {
+	m.ownTags()
 	for i, tag := range m.tags {
 		if key > tag.Key {
 			continue
@@ -176,6 +197,7 @@ func (m *metric) GetTag(key string) (string, bool) {
 func (m *metric) RemoveTag(key string) {
 	for i, tag := range m.tags {
 		if tag.Key == key {
+			m.ownTags()
 			copy(m.tags[i:], m.tags[i+1:])
 			m.tags[len(m.tags)-1] = nil
 			m.tags = m.tags[:len(m.tags)-1]
@@ -185,6 +207,7 @@ func (m *metric) RemoveTag(key string) {
 }
 
 func (m *metric) AddField(key string, value interface{}) {
+	m.ownFields()
 	for i, field := range m.fields {
 		if key == field.Key {
 			m.fields[i] = &internal.Field{Key: key, Value: convertField(value)}
@@ -194,24 +217,100 @@ func (m *metric) AddField(key string, value interface{}) {
 	m.fields = append(m.fields, &internal.Field{Key: key, Value: convertField(value)})
 }
 
+func (m *metric) HasField(key string) bool {
+	for _, field := range m.fields {
+		if field.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *metric) GetField(key string) (interface{}, bool) {
+	for _, field := range m.fields {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return nil, false
+}
+
+func (m *metric) RemoveField(key string) {
+	for i, field := range m.fields {
+		if field.Key == key {
+			m.ownFields()
+			copy(m.fields[i:], m.fields[i+1:])
+			m.fields[len(m.fields)-1] = nil
+			m.fields = m.fields[:len(m.fields)-1]
+			return
+		}
+	}
+}
+
+// HashID returns the FNV-1a hash of the name and the tags, which are kept
+// sorted by key.
+func (m *metric) HashID() uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(m.name))
+	h.Write([]byte{0})
+	for _, tag := range m.tags {
+		h.Write([]byte(tag.Key))
+		h.Write([]byte{0})
+		h.Write([]byte(tag.Value))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+// Copy returns a copy of the metric that shares its tag and field lists with
+// the original until either one is modified.
 func (m *metric) Copy() internal.Metric {
+	m.tagsShared = true
+	m.fieldsShared = true
+
 	m2 := &metric{
-		name:      m.name,
-		tags:      make([]*internal.Tag, len(m.tags)),
-		fields:    make([]*internal.Field, len(m.fields)),
-		tm:        m.tm,
-		tp:        m.tp,
-		aggregate: m.aggregate,
+		name:         m.name,
+		tags:         m.tags,
+		fields:       m.fields,
+		tm:           m.tm,
+		tp:           m.tp,
+		aggregate:    m.aggregate,
+		tagsShared:   true,
+		fieldsShared: true,
+	}
+	return m2
+}
+
+// ownTags replaces a shared tag list with a private deep copy.
+func (m *metric) ownTags() {
+	if !m.tagsShared {
+		return
 	}
 
+	tagBuf := make([]internal.Tag, len(m.tags))
+	tags := make([]*internal.Tag, len(m.tags))
 	for i, tag := range m.tags {
-		m2.tags[i] = &internal.Tag{Key: tag.Key, Value: tag.Value}
+		tagBuf[i] = *tag
+		tags[i] = &tagBuf[i]
+	}
+	m.tags = tags
+	m.tagsShared = false
+}
+
+// ownFields replaces a shared field list with a private deep copy.
+func (m *metric) ownFields() {
+	if !m.fieldsShared {
+		return
 	}
 
+	fieldBuf := make([]internal.Field, len(m.fields))
+	fields := make([]*internal.Field, len(m.fields))
 	for i, field := range m.fields {
-		m2.fields[i] = &internal.Field{Key: field.Key, Value: field.Value}
+		fieldBuf[i] = *field
+		fields[i] = &fieldBuf[i]
 	}
-	return m2
+	m.fields = fields
+	m.fieldsShared = false
 }
 
 func (m *metric) Accept() {
@@ -223,23 +322,27 @@ func (m *metric) Reject() {
 func (m *metric) Drop() {
 }
 
+// sortTags sorts tags by key.  Tag lists are short so an insertion sort is
+// used, it avoids the allocations made by sort.Slice.
+func sortTags(tags []internal.Tag) {
+	for i := 1; i < len(tags); i++ {
+		for j := i; j > 0 && tags[j].Key < tags[j-1].Key; j-- {
+			tags[j], tags[j-1] = tags[j-1], tags[j]
+		}
+	}
+}
+
 // Convert field to a supported type or nil if unconvertible
 func convertField(v interface{}) interface{} {
 	switch v := v.(type) {
-	case float64:
-		return v
-	case int64:
-		return v
-	case string:
-		return v
-	case bool:
+	case float64, int64, uint64, string, bool:
+		// Already a supported type, returning the original interface value
+		// avoids boxing it again.
 		return v
 	case int:
 		return int64(v)
 	case uint:
 		return uint64(v)
-	case uint64:
-		return uint64(v)
 	case []byte:
 		return string(v)
 	case int32:
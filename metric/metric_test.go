@@ -0,0 +1,140 @@
+package metric
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSortsTags(t *testing.T) {
+	now := time.Now()
+
+	m, err := New("cpu",
+		map[string]string{"host": "localhost", "cpu": "cpu0", "dc": "east"},
+		map[string]interface{}{"usage_idle": 99.0},
+		now)
+	require.NoError(t, err)
+
+	keys := make([]string, 0, len(m.TagList()))
+	for _, tag := range m.TagList() {
+		keys = append(keys, tag.Key)
+	}
+	require.Equal(t, []string{"cpu", "dc", "host"}, keys)
+}
+
+func TestNewDropsUnsupportedFields(t *testing.T) {
+	m, err := New("cpu",
+		nil,
+		map[string]interface{}{"usage_idle": 99, "bad": struct{}{}},
+		time.Now())
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]interface{}{"usage_idle": int64(99)}, m.Fields())
+}
+
+func TestCopyOnWrite(t *testing.T) {
+	m, err := New("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"usage_idle": 99.0},
+		time.Now())
+	require.NoError(t, err)
+
+	m2 := m.Copy()
+	m2.AddTag("host", "example.org")
+	m2.AddTag("cpu", "cpu0")
+	m2.(*metric).AddField("usage_idle", 42.0)
+
+	require.Equal(t, map[string]string{"host": "localhost"}, m.Tags())
+	require.Equal(t, map[string]interface{}{"usage_idle": 99.0}, m.Fields())
+	require.Equal(t, map[string]string{"host": "example.org", "cpu": "cpu0"}, m2.Tags())
+	require.Equal(t, map[string]interface{}{"usage_idle": 42.0}, m2.Fields())
+
+	// The original is also protected from changes made after copying.
+	m.RemoveTag("host")
+	require.Equal(t, map[string]string{}, m.Tags())
+	require.Equal(t, map[string]string{"host": "example.org", "cpu": "cpu0"}, m2.Tags())
+}
+
+func TestCopySharesUntilModified(t *testing.T) {
+	m, err := New("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"usage_idle": 99.0},
+		time.Now())
+	require.NoError(t, err)
+
+	m2 := m.Copy()
+	require.True(t, m.TagList()[0] == m2.TagList()[0])
+	require.True(t, m.FieldList()[0] == m2.FieldList()[0])
+
+	m2.SetName("cpu2")
+	require.Equal(t, "cpu", m.Name())
+	require.True(t, m.TagList()[0] == m2.TagList()[0])
+}
+
+func BenchmarkNew(b *testing.B) {
+	now := time.Now()
+	tags := map[string]string{
+		"host": "localhost",
+		"cpu":  "cpu0",
+	}
+	fields := map[string]interface{}{
+		"usage_user":   0.5,
+		"usage_system": 0.25,
+		"usage_idle":   99.25,
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		New("cpu", tags, fields, now, internal.Gauge)
+	}
+}
+
+func BenchmarkCopy(b *testing.B) {
+	m, _ := New("cpu",
+		map[string]string{"host": "localhost", "cpu": "cpu0"},
+		map[string]interface{}{"usage_user": 0.5, "usage_idle": 99.5},
+		time.Now())
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m.Copy()
+	}
+}
+
+func BenchmarkCopyAndModify(b *testing.B) {
+	m, _ := New("cpu",
+		map[string]string{"host": "localhost", "cpu": "cpu0"},
+		map[string]interface{}{"usage_user": 0.5, "usage_idle": 99.5},
+		time.Now())
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m2 := m.Copy()
+		m2.AddTag("dc", "east")
+	}
+}
+
+func TestHashID(t *testing.T) {
+	now := time.Now()
+
+	m1, _ := New("cpu",
+		map[string]string{"host": "localhost", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 99.0},
+		now)
+	m2, _ := New("cpu",
+		map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_user": 1.0},
+		now.Add(time.Second))
+	m2.AddTag("host", "localhost")
+	require.Equal(t, m1.HashID(), m2.HashID())
+
+	m2.AddTag("host", "remote")
+	require.NotEqual(t, m1.HashID(), m2.HashID())
+
+	// Name and tag boundaries are part of the identity.
+	m3, _ := New("cpuc", map[string]string{"pu": "cpu0"}, map[string]interface{}{"v": 1.0}, now)
+	m4, _ := New("cpu", map[string]string{"cpu": "cpu0"}, map[string]interface{}{"v": 1.0}, now)
+	require.NotEqual(t, m3.HashID(), m4.HashID())
+}
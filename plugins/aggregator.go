@@ -0,0 +1,23 @@
+package plugins
+
+import (
+	"github.com/geekflow/straw/internal"
+)
+
+// Aggregator computes new metrics from the metrics of an input or an output
+// over each period.
+type Aggregator interface {
+	SampleConfig() string
+	Description() string
+
+	// Add adds a metric of the current period.  The aggregator must not
+	// modify the metric, and must copy it to keep it after Add returns.
+	Add(in internal.Metric)
+
+	// Push returns the metrics aggregated over the current period.  Metrics
+	// without a timestamp are set to the end of the period.
+	Push() []internal.Metric
+
+	// Reset clears the aggregator for the next period.
+	Reset()
+}
@@ -0,0 +1,11 @@
+package all
+
+import (
+	_ "github.com/geekflow/straw/plugins/aggregators/basicstats"
+	_ "github.com/geekflow/straw/plugins/aggregators/final"
+	_ "github.com/geekflow/straw/plugins/aggregators/histogram"
+	_ "github.com/geekflow/straw/plugins/aggregators/merge"
+	_ "github.com/geekflow/straw/plugins/aggregators/quantile"
+	_ "github.com/geekflow/straw/plugins/aggregators/topk"
+	_ "github.com/geekflow/straw/plugins/aggregators/valuecounter"
+)
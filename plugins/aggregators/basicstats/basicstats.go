@@ -0,0 +1,228 @@
+package basicstats
+
+import (
+	"fmt"
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/metric"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/aggregators"
+	"math"
+	"time"
+)
+
+var sampleConfig = `
+  ## Length of the periods over which the statistics are computed.
+  period = "30s"
+
+  ## Drop the original metrics instead of passing them on.
+  drop_original = false
+
+  ## Statistics to compute for each numeric field, any of "count", "min",
+  ## "max", "mean", "sum", "stdev", "s2", "first", "last" and
+  ## "non_negative_rate".
+  # stats = ["count", "min", "max", "mean", "stdev", "s2"]
+`
+
+var defaultStats = []string{"count", "min", "max", "mean", "stdev", "s2"}
+
+// BasicStats computes statistics of the numeric fields of each series over
+// each period.
+type BasicStats struct {
+	Stats []string `toml:"stats"`
+
+	series map[uint64]*series
+	// order is the order in which the series were first seen.
+	order []uint64
+}
+
+// series holds the statistics of a series within the current period.
+type series struct {
+	name   string
+	tags   map[string]string
+	fields map[string]*stats
+	// order is the order in which the fields were first seen.
+	order []string
+}
+
+// stats holds the running statistics of a field.  The mean and the sum of
+// squared differences from it are updated with Welford's algorithm.
+type stats struct {
+	count     int64
+	min, max  float64
+	sum       float64
+	mean      float64
+	m2        float64
+	first     float64
+	firstTime time.Time
+	last      float64
+	lastTime  time.Time
+}
+
+func (*BasicStats) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*BasicStats) Description() string {
+	return "Compute basic statistics of each numeric field over each period"
+}
+
+func (b *BasicStats) Init() error {
+	if b.Stats == nil {
+		b.Stats = defaultStats
+	}
+	for _, stat := range b.Stats {
+		switch stat {
+		case "count", "min", "max", "mean", "sum", "stdev", "s2",
+			"first", "last", "non_negative_rate":
+		default:
+			return fmt.Errorf("basicstats: invalid stat %q", stat)
+		}
+	}
+
+	b.Reset()
+	return nil
+}
+
+func (b *BasicStats) Add(in internal.Metric) {
+	id := in.HashID()
+	s, ok := b.series[id]
+	if !ok {
+		s = &series{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]*stats),
+		}
+		b.series[id] = s
+		b.order = append(b.order, id)
+	}
+
+	for _, field := range in.FieldList() {
+		value, ok := toFloat(field.Value)
+		if !ok {
+			continue
+		}
+
+		st, ok := s.fields[field.Key]
+		if !ok {
+			s.fields[field.Key] = &stats{
+				count:     1,
+				min:       value,
+				max:       value,
+				sum:       value,
+				mean:      value,
+				first:     value,
+				firstTime: in.Time(),
+				last:      value,
+				lastTime:  in.Time(),
+			}
+			s.order = append(s.order, field.Key)
+			continue
+		}
+		st.add(value, in.Time())
+	}
+}
+
+func (st *stats) add(value float64, t time.Time) {
+	st.count++
+	st.min = math.Min(st.min, value)
+	st.max = math.Max(st.max, value)
+	st.sum += value
+
+	delta := value - st.mean
+	st.mean += delta / float64(st.count)
+	st.m2 += delta * (value - st.mean)
+
+	if t.Before(st.firstTime) {
+		st.first, st.firstTime = value, t
+	}
+	if !t.Before(st.lastTime) {
+		st.last, st.lastTime = value, t
+	}
+}
+
+func (b *BasicStats) Push() []internal.Metric {
+	var out []internal.Metric
+	for _, id := range b.order {
+		s := b.series[id]
+		fields := make(map[string]interface{})
+		for _, key := range s.order {
+			st := s.fields[key]
+			for _, stat := range b.Stats {
+				if v, ok := st.value(stat); ok {
+					fields[key+"_"+stat] = v
+				}
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		m, err := metric.New(s.name, s.tags, fields, time.Time{})
+		if err != nil {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// value returns a statistic, or false if it is not defined for the values
+// seen.  The variance is that of a sample, so it needs two values.
+func (st *stats) value(stat string) (interface{}, bool) {
+	switch stat {
+	case "count":
+		return st.count, true
+	case "min":
+		return st.min, true
+	case "max":
+		return st.max, true
+	case "mean":
+		return st.mean, true
+	case "sum":
+		return st.sum, true
+	case "s2", "stdev":
+		if st.count < 2 {
+			return nil, false
+		}
+		s2 := st.m2 / float64(st.count-1)
+		if stat == "stdev" {
+			return math.Sqrt(s2), true
+		}
+		return s2, true
+	case "first":
+		return st.first, true
+	case "last":
+		return st.last, true
+	case "non_negative_rate":
+		elapsed := st.lastTime.Sub(st.firstTime).Seconds()
+		diff := st.last - st.first
+		if elapsed <= 0 || diff < 0 {
+			return nil, false
+		}
+		return diff / elapsed, true
+	}
+	return nil, false
+}
+
+func (b *BasicStats) Reset() {
+	b.series = make(map[uint64]*series)
+	b.order = nil
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func init() {
+	aggregators.Add("basicstats", func() plugins.Aggregator {
+		return &BasicStats{}
+	})
+}
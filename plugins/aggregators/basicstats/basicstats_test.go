@@ -0,0 +1,71 @@
+package basicstats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBasicStats(t *testing.T) {
+	b := &BasicStats{Stats: []string{"count", "min", "max", "mean", "sum",
+		"stdev", "s2", "first", "last", "non_negative_rate"}}
+	require.NoError(t, b.Init())
+
+	tags := map[string]string{"host": "web01"}
+	b.Add(testutil.MustMetric("disk", tags,
+		map[string]interface{}{"used": int64(4), "mode": "rw"}, time.Unix(10, 0)))
+	b.Add(testutil.MustMetric("disk", tags,
+		map[string]interface{}{"used": uint64(8)}, time.Unix(20, 0)))
+	// Out of order, so neither first nor last.
+	b.Add(testutil.MustMetric("disk", tags,
+		map[string]interface{}{"used": 6.0}, time.Unix(15, 0)))
+
+	out := b.Push()
+	require.Len(t, out, 1)
+	require.Equal(t, "disk", out[0].Name())
+	require.Equal(t, tags, out[0].Tags())
+	require.True(t, out[0].Time().IsZero())
+	require.Equal(t, map[string]interface{}{
+		"used_count":             int64(3),
+		"used_min":               4.0,
+		"used_max":               8.0,
+		"used_mean":              6.0,
+		"used_sum":               18.0,
+		"used_stdev":             2.0,
+		"used_s2":                4.0,
+		"used_first":             4.0,
+		"used_last":              8.0,
+		"used_non_negative_rate": 0.4,
+	}, out[0].Fields())
+}
+
+func TestBasicStatsSeries(t *testing.T) {
+	b := &BasicStats{Stats: []string{"count", "stdev", "non_negative_rate"}}
+	require.NoError(t, b.Init())
+
+	b.Add(testutil.MustMetric("cpu", map[string]string{"cpu": "0"},
+		map[string]interface{}{"time_user": 10.0}, time.Unix(10, 0)))
+	b.Add(testutil.MustMetric("cpu", map[string]string{"cpu": "1"},
+		map[string]interface{}{"time_user": 10.0}, time.Unix(10, 0)))
+	b.Add(testutil.MustMetric("cpu", map[string]string{"cpu": "1"},
+		map[string]interface{}{"time_user": 5.0}, time.Unix(20, 0)))
+
+	// A single value has no deviation or rate, a decreasing one no
+	// non-negative rate.
+	out := b.Push()
+	require.Len(t, out, 2)
+	require.Equal(t, map[string]interface{}{"time_user_count": int64(1)}, out[0].Fields())
+	require.Equal(t, "1", out[1].Tags()["cpu"])
+	require.Equal(t, int64(2), out[1].Fields()["time_user_count"])
+	require.NotContains(t, out[1].Fields(), "time_user_non_negative_rate")
+
+	b.Reset()
+	require.Len(t, b.Push(), 0)
+}
+
+func TestInvalidStat(t *testing.T) {
+	b := &BasicStats{Stats: []string{"median"}}
+	require.Error(t, b.Init())
+}
@@ -0,0 +1,113 @@
+package final
+
+import (
+	"fmt"
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/aggregators"
+	"time"
+)
+
+var sampleConfig = `
+  ## Interval at which quiet series are checked for.
+  period = "30s"
+
+  ## Drop the original metrics, so that only the final metrics are passed
+  ## on.
+  drop_original = true
+
+  ## A series is quiet, and its last metric is emitted, when it has not been
+  ## added to for this long.
+  series_timeout = "5m"
+`
+
+// Final emits the last metric of each series once the series goes quiet.
+// Unlike other aggregators its state is kept across periods.
+type Final struct {
+	SeriesTimeout internal.Duration `toml:"series_timeout"`
+
+	clock  internal.Clock
+	series map[uint64]*series
+	// order is the order in which the series were first seen.
+	order []uint64
+}
+
+// series holds the last metric of a series and when it was added.
+type series struct {
+	metric internal.Metric
+	seen   time.Time
+}
+
+func (*Final) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Final) Description() string {
+	return "Emit the last metric of each series once it goes quiet"
+}
+
+func (f *Final) Init() error {
+	if f.SeriesTimeout.Duration <= 0 {
+		return fmt.Errorf("final: series_timeout must be positive")
+	}
+	if f.clock == nil {
+		f.clock = internal.RealClock
+	}
+	f.series = make(map[uint64]*series)
+	f.order = nil
+	return nil
+}
+
+func (f *Final) Add(in internal.Metric) {
+	id := in.HashID()
+	s, ok := f.series[id]
+	if !ok {
+		s = &series{}
+		f.series[id] = s
+		f.order = append(f.order, id)
+	}
+	s.metric = in.Copy()
+	s.seen = f.clock.Now()
+}
+
+// Push returns the last metric of each quiet series and forgets the series.
+func (f *Final) Push() []internal.Metric {
+	now := f.clock.Now()
+	var out []internal.Metric
+	order := f.order[:0]
+	for _, id := range f.order {
+		s := f.series[id]
+		if now.Sub(s.seen) < f.SeriesTimeout.Duration {
+			order = append(order, id)
+			continue
+		}
+		out = append(out, s.metric)
+		delete(f.series, id)
+	}
+	f.order = order
+	return out
+}
+
+// Flush returns the last metric of every series, quiet or not, and forgets
+// the series.  It is called when the agent stops.
+func (f *Final) Flush() []internal.Metric {
+	out := make([]internal.Metric, 0, len(f.order))
+	for _, id := range f.order {
+		out = append(out, f.series[id].metric)
+	}
+	f.series = make(map[uint64]*series)
+	f.order = nil
+	return out
+}
+
+// Reset keeps the series, which are only forgotten once pushed.
+func (f *Final) Reset() {
+}
+
+func init() {
+	aggregators.Add("final", func() plugins.Aggregator {
+		return &Final{
+			SeriesTimeout: internal.Duration{Duration: 5 * time.Minute},
+		}
+	})
+}
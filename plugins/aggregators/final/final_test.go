@@ -0,0 +1,65 @@
+package final
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFinal(t *testing.T) {
+	clock := testutil.NewClock(time.Unix(0, 0))
+	f := &Final{SeriesTimeout: internal.Duration{Duration: time.Minute}, clock: clock}
+	require.NoError(t, f.Init())
+
+	add := func(host string, value float64) {
+		f.Add(testutil.MustMetric("event", map[string]string{"host": host},
+			map[string]interface{}{"value": value}, clock.Now()))
+	}
+	add("web01", 1)
+	add("web02", 1)
+	clock.Add(30 * time.Second)
+	add("web01", 2)
+	require.Len(t, f.Push(), 0)
+	f.Reset()
+
+	// web02 has been quiet for a minute, web01 only for 30 seconds.
+	clock.Add(30 * time.Second)
+	out := f.Push()
+	require.Len(t, out, 1)
+	require.Equal(t, "web02", out[0].Tags()["host"])
+	require.Equal(t, time.Unix(0, 0), out[0].Time())
+
+	clock.Add(30 * time.Second)
+	out = f.Push()
+	require.Len(t, out, 1)
+	require.Equal(t, map[string]interface{}{"value": 2.0}, out[0].Fields())
+	require.Equal(t, time.Unix(30, 0), out[0].Time())
+
+	clock.Add(time.Hour)
+	require.Len(t, f.Push(), 0)
+}
+
+func TestFinalFlush(t *testing.T) {
+	clock := testutil.NewClock(time.Unix(0, 0))
+	f := &Final{SeriesTimeout: internal.Duration{Duration: time.Minute}, clock: clock}
+	require.NoError(t, f.Init())
+
+	f.Add(testutil.MustMetric("event", map[string]string{"host": "web01"},
+		map[string]interface{}{"value": 1.0}, clock.Now()))
+	f.Add(testutil.MustMetric("event", map[string]string{"host": "web02"},
+		map[string]interface{}{"value": 2.0}, clock.Now()))
+	require.Len(t, f.Push(), 0)
+
+	// Active series are emitted when the agent stops.
+	out := f.Flush()
+	require.Len(t, out, 2)
+	require.Equal(t, "web01", out[0].Tags()["host"])
+	require.Equal(t, "web02", out[1].Tags()["host"])
+
+	require.Len(t, f.Flush(), 0)
+	clock.Add(time.Hour)
+	require.Len(t, f.Push(), 0)
+}
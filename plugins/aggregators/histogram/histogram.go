@@ -0,0 +1,238 @@
+package histogram
+
+import (
+	"fmt"
+	"github.com/geekflow/straw/filter"
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/metric"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/aggregators"
+	"strconv"
+	"time"
+)
+
+var sampleConfig = `
+  ## Length of the periods over which the values are counted.
+  period = "30s"
+
+  ## Drop the original metrics instead of passing them on.
+  drop_original = false
+
+  ## Emit cumulative counts, of the values less than or equal to each bucket
+  ## boundary, tagged "le".  Otherwise each bucket counts the values between
+  ## its boundaries, tagged "gt" and "le".
+  cumulative = true
+
+  ## The bucket boundaries of the fields of each measurement, in increasing
+  ## order; a "+Inf" bucket is always added.  Fields support glob patterns,
+  ## by default all numeric fields are counted.
+  [[inputs.procstat.aggregators.histogram.config]]
+    measurement_name = "procstat"
+    fields = ["cpu_usage"]
+    buckets = [1.0, 5.0, 10.0, 50.0, 100.0]
+`
+
+// Histogram counts the values of fields in buckets over each period.
+type Histogram struct {
+	Configs    []BucketConfig `toml:"config"`
+	Cumulative bool           `toml:"cumulative"`
+
+	series map[uint64]*series
+	// order is the order in which the series were first seen.
+	order []uint64
+}
+
+// BucketConfig sets the buckets of the fields of a measurement.
+type BucketConfig struct {
+	Measurement string    `toml:"measurement_name"`
+	Fields      []string  `toml:"fields"`
+	Buckets     []float64 `toml:"buckets"`
+
+	fields filter.Filter
+}
+
+// series holds the counts of the fields of a series within the current
+// period.
+type series struct {
+	name   string
+	tags   map[string]string
+	fields map[string]*counts
+	// order is the order in which the fields were first seen.
+	order []string
+}
+
+// counts are the number of values in each bucket; the last one is +Inf.
+type counts struct {
+	buckets []float64
+	counts  []int64
+}
+
+func (*Histogram) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Histogram) Description() string {
+	return "Count the values of fields in buckets over each period"
+}
+
+func (h *Histogram) Init() error {
+	if len(h.Configs) == 0 {
+		return fmt.Errorf("histogram: no config")
+	}
+	for i := range h.Configs {
+		c := &h.Configs[i]
+		if c.Measurement == "" {
+			return fmt.Errorf("histogram: missing measurement_name")
+		}
+		if len(c.Buckets) == 0 {
+			return fmt.Errorf("histogram: %s: no buckets", c.Measurement)
+		}
+		for j := 1; j < len(c.Buckets); j++ {
+			if c.Buckets[j] <= c.Buckets[j-1] {
+				return fmt.Errorf("histogram: %s: buckets must be increasing", c.Measurement)
+			}
+		}
+
+		var err error
+		c.fields, err = filter.Compile(c.Fields)
+		if err != nil {
+			return fmt.Errorf("histogram: %v", err)
+		}
+	}
+
+	h.Reset()
+	return nil
+}
+
+// buckets returns the buckets of a field, or nil if it is not counted.  The
+// first matching config applies.
+func (h *Histogram) buckets(name, field string) []float64 {
+	for _, c := range h.Configs {
+		if c.Measurement == name && (c.fields == nil || c.fields.Match(field)) {
+			return c.Buckets
+		}
+	}
+	return nil
+}
+
+func (h *Histogram) Add(in internal.Metric) {
+	id := in.HashID()
+	for _, field := range in.FieldList() {
+		value, ok := toFloat(field.Value)
+		if !ok {
+			continue
+		}
+		if c := h.fieldCounts(id, in, field.Key); c != nil {
+			c.add(value)
+		}
+	}
+}
+
+// fieldCounts returns the counts of a field of a series, starting them when
+// the field is first seen in the period, or nil if the field is not counted.
+func (h *Histogram) fieldCounts(id uint64, in internal.Metric, field string) *counts {
+	s, ok := h.series[id]
+	if ok {
+		if c, ok := s.fields[field]; ok {
+			return c
+		}
+	}
+
+	buckets := h.buckets(in.Name(), field)
+	if buckets == nil {
+		return nil
+	}
+
+	if !ok {
+		s = &series{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]*counts),
+		}
+		h.series[id] = s
+		h.order = append(h.order, id)
+	}
+	c := &counts{buckets: buckets, counts: make([]int64, len(buckets)+1)}
+	s.fields[field] = c
+	s.order = append(s.order, field)
+	return c
+}
+
+func (c *counts) add(value float64) {
+	for i, b := range c.buckets {
+		if value <= b {
+			c.counts[i]++
+			return
+		}
+	}
+	c.counts[len(c.buckets)]++
+}
+
+func (h *Histogram) Push() []internal.Metric {
+	var out []internal.Metric
+	for _, id := range h.order {
+		s := h.series[id]
+		for _, key := range s.order {
+			c := s.fields[key]
+			var total int64
+			for i, n := range c.counts {
+				tags := make(map[string]string, len(s.tags)+2)
+				for k, v := range s.tags {
+					tags[k] = v
+				}
+
+				le := "+Inf"
+				if i < len(c.buckets) {
+					le = formatBound(c.buckets[i])
+				}
+				tags["le"] = le
+
+				total += n
+				count := n
+				if h.Cumulative {
+					count = total
+				} else if i == 0 {
+					tags["gt"] = "-Inf"
+				} else {
+					tags["gt"] = formatBound(c.buckets[i-1])
+				}
+
+				m, err := metric.New(s.name, tags,
+					map[string]interface{}{key + "_bucket": count},
+					time.Time{}, internal.Histogram)
+				if err != nil {
+					continue
+				}
+				out = append(out, m)
+			}
+		}
+	}
+	return out
+}
+
+func (h *Histogram) Reset() {
+	h.series = make(map[uint64]*series)
+	h.order = nil
+}
+
+func formatBound(b float64) string {
+	return strconv.FormatFloat(b, 'f', -1, 64)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func init() {
+	aggregators.Add("histogram", func() plugins.Aggregator {
+		return &Histogram{Cumulative: true}
+	})
+}
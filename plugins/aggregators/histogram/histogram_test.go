@@ -0,0 +1,66 @@
+package histogram
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newHistogram(t *testing.T, cumulative bool) *Histogram {
+	h := &Histogram{
+		Cumulative: cumulative,
+		Configs: []BucketConfig{{
+			Measurement: "procstat",
+			Fields:      []string{"cpu_*"},
+			Buckets:     []float64{1, 10},
+		}},
+	}
+	require.NoError(t, h.Init())
+
+	for _, v := range []float64{0.5, 1, 5, 50} {
+		h.Add(testutil.MustMetric("procstat", map[string]string{"process_name": "nginx"},
+			map[string]interface{}{"cpu_usage": v, "memory_rss": int64(1024)},
+			time.Unix(0, 0)))
+	}
+	h.Add(testutil.MustMetric("mem", nil,
+		map[string]interface{}{"cpu_usage": 1.0}, time.Unix(0, 0)))
+	return h
+}
+
+// buckets returns the count of each bucket by its tags.
+func buckets(out []internal.Metric) map[string]int64 {
+	counts := make(map[string]int64)
+	for _, m := range out {
+		key := m.Tags()["le"]
+		if gt, ok := m.Tags()["gt"]; ok {
+			key = gt + "," + key
+		}
+		counts[key] = m.Fields()["cpu_usage_bucket"].(int64)
+	}
+	return counts
+}
+
+func TestCumulative(t *testing.T) {
+	out := newHistogram(t, true).Push()
+	require.Len(t, out, 3)
+	require.Equal(t, internal.Histogram, out[0].Type())
+	require.Equal(t, "nginx", out[0].Tags()["process_name"])
+	require.Equal(t, map[string]int64{"1": 2, "10": 3, "+Inf": 4}, buckets(out))
+}
+
+func TestNonCumulative(t *testing.T) {
+	h := newHistogram(t, false)
+	out := h.Push()
+	require.Equal(t, map[string]int64{"-Inf,1": 2, "1,10": 1, "10,+Inf": 1}, buckets(out))
+
+	h.Reset()
+	require.Len(t, h.Push(), 0)
+}
+
+func TestInvalidBuckets(t *testing.T) {
+	h := &Histogram{Configs: []BucketConfig{{Measurement: "cpu", Buckets: []float64{10, 1}}}}
+	require.Error(t, h.Init())
+}
@@ -0,0 +1,99 @@
+package merge
+
+import (
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/metric"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/aggregators"
+)
+
+var sampleConfig = `
+  ## Length of the periods after which the merged metrics are emitted.
+  period = "30s"
+
+  ## Drop the original metrics, so that only the merged metrics are passed
+  ## on.
+  drop_original = true
+`
+
+// Merge combines the metrics of a series with the same timestamp into one
+// metric with the fields of all of them.
+type Merge struct {
+	metrics map[key]*merged
+	// order is the order in which the keys were first seen.
+	order []key
+}
+
+// key identifies the metrics merged together.
+type key struct {
+	id   uint64
+	time int64
+}
+
+type merged struct {
+	metric internal.Metric
+	// mixed is set if the metrics have different value types.
+	mixed bool
+}
+
+func (*Merge) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Merge) Description() string {
+	return "Merge metrics of the same series and timestamp into one metric"
+}
+
+func (m *Merge) Init() error {
+	m.Reset()
+	return nil
+}
+
+func (m *Merge) Add(in internal.Metric) {
+	k := key{id: in.HashID(), time: in.Time().UnixNano()}
+	e, ok := m.metrics[k]
+	if !ok {
+		m.metrics[k] = &merged{metric: in.Copy()}
+		m.order = append(m.order, k)
+		return
+	}
+
+	if in.Type() != e.metric.Type() {
+		e.mixed = true
+	}
+	for _, field := range in.FieldList() {
+		e.metric.AddField(field.Key, field.Value)
+	}
+}
+
+// Push returns the merged metrics; metrics merged from different value types
+// are untyped.
+func (m *Merge) Push() []internal.Metric {
+	out := make([]internal.Metric, 0, len(m.order))
+	for _, k := range m.order {
+		e := m.metrics[k]
+		if !e.mixed {
+			out = append(out, e.metric)
+			continue
+		}
+
+		u, err := metric.New(e.metric.Name(), e.metric.Tags(), e.metric.Fields(),
+			e.metric.Time(), internal.Untyped)
+		if err != nil {
+			continue
+		}
+		out = append(out, u)
+	}
+	return out
+}
+
+func (m *Merge) Reset() {
+	m.metrics = make(map[key]*merged)
+	m.order = nil
+}
+
+func init() {
+	aggregators.Add("merge", func() plugins.Aggregator {
+		return &Merge{}
+	})
+}
@@ -0,0 +1,53 @@
+package merge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	m := &Merge{}
+	require.NoError(t, m.Init())
+
+	tags := map[string]string{"host": "web01"}
+	m.Add(testutil.MustMetric("system", tags,
+		map[string]interface{}{"load1": 0.5}, time.Unix(10, 0), internal.Gauge))
+	m.Add(testutil.MustMetric("system", tags,
+		map[string]interface{}{"uptime": uint64(100)}, time.Unix(10, 0), internal.Counter))
+	m.Add(testutil.MustMetric("system", map[string]string{"host": "web02"},
+		map[string]interface{}{"load1": 1.5}, time.Unix(10, 0), internal.Gauge))
+	m.Add(testutil.MustMetric("system", tags,
+		map[string]interface{}{"load1": 0.7}, time.Unix(20, 0), internal.Gauge))
+	m.Add(testutil.MustMetric("system", tags,
+		map[string]interface{}{"n_cpus": int64(4)}, time.Unix(20, 0), internal.Gauge))
+
+	out := m.Push()
+	require.Len(t, out, 3)
+	require.Equal(t, map[string]interface{}{"load1": 0.5, "uptime": uint64(100)}, out[0].Fields())
+	require.Equal(t, internal.Untyped, out[0].Type())
+	require.Equal(t, time.Unix(10, 0), out[0].Time())
+	require.Equal(t, "web02", out[1].Tags()["host"])
+	require.Equal(t, map[string]interface{}{"load1": 0.7, "n_cpus": int64(4)}, out[2].Fields())
+	require.Equal(t, internal.Gauge, out[2].Type())
+
+	m.Reset()
+	require.Len(t, m.Push(), 0)
+}
+
+func TestMergeKeepsOriginal(t *testing.T) {
+	m := &Merge{}
+	require.NoError(t, m.Init())
+
+	in := testutil.MustMetric("net", nil,
+		map[string]interface{}{"tcp_inerrs": int64(0)}, time.Unix(10, 0))
+	m.Add(in)
+	m.Add(testutil.MustMetric("net", nil,
+		map[string]interface{}{"udp_inerrs": int64(0)}, time.Unix(10, 0)))
+
+	require.Len(t, m.Push()[0].Fields(), 2)
+	require.Len(t, in.Fields(), 1)
+}
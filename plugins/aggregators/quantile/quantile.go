@@ -0,0 +1,168 @@
+package quantile
+
+import (
+	"fmt"
+	"github.com/geekflow/straw/filter"
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/metric"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/aggregators"
+	"math"
+	"strconv"
+	"time"
+)
+
+var sampleConfig = `
+  ## Length of the periods over which the quantiles are estimated.
+  period = "30s"
+
+  ## Drop the original metrics instead of passing them on.
+  drop_original = false
+
+  ## Quantiles to emit for each field, as fields named after the percentile,
+  ## for example "response_time_p99".
+  # quantiles = [0.5, 0.9, 0.99]
+
+  ## Fields to estimate the quantiles of, glob patterns are supported.  By
+  ## default all numeric fields.
+  # fields = []
+
+  ## Compression of the t-digest sketch; higher values are more accurate and
+  ## use more memory.
+  # compression = 100.0
+`
+
+// Quantile estimates quantiles of the values of fields over each period.
+type Quantile struct {
+	Quantiles   []float64 `toml:"quantiles"`
+	Fields      []string  `toml:"fields"`
+	Compression float64   `toml:"compression"`
+
+	fields   filter.Filter
+	suffixes []string
+	series   map[uint64]*series
+	// order is the order in which the series were first seen.
+	order []uint64
+}
+
+// series holds the sketches of the fields of a series within the current
+// period.
+type series struct {
+	name    string
+	tags    map[string]string
+	digests map[string]*digest
+	// order is the order in which the fields were first seen.
+	order []string
+}
+
+func (*Quantile) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Quantile) Description() string {
+	return "Estimate quantiles of fields over each period"
+}
+
+func (q *Quantile) Init() error {
+	if len(q.Quantiles) == 0 {
+		return fmt.Errorf("quantile: no quantiles")
+	}
+	if q.Compression < 1 {
+		return fmt.Errorf("quantile: compression must be at least 1")
+	}
+
+	q.suffixes = make([]string, len(q.Quantiles))
+	for i, quantile := range q.Quantiles {
+		if quantile < 0 || quantile > 1 {
+			return fmt.Errorf("quantile: quantile %v not between 0 and 1", quantile)
+		}
+		q.suffixes[i] = "_p" + strconv.FormatFloat(quantile*100, 'f', -1, 64)
+	}
+
+	var err error
+	q.fields, err = filter.Compile(q.Fields)
+	if err != nil {
+		return fmt.Errorf("quantile: %v", err)
+	}
+
+	q.Reset()
+	return nil
+}
+
+func (q *Quantile) Add(in internal.Metric) {
+	id := in.HashID()
+	for _, field := range in.FieldList() {
+		if q.fields != nil && !q.fields.Match(field.Key) {
+			continue
+		}
+		value, ok := toFloat(field.Value)
+		if !ok || math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+
+		s, ok := q.series[id]
+		if !ok {
+			s = &series{
+				name:    in.Name(),
+				tags:    in.Tags(),
+				digests: make(map[string]*digest),
+			}
+			q.series[id] = s
+			q.order = append(q.order, id)
+		}
+		d, ok := s.digests[field.Key]
+		if !ok {
+			d = newDigest(q.Compression)
+			s.digests[field.Key] = d
+			s.order = append(s.order, field.Key)
+		}
+		d.add(value)
+	}
+}
+
+func (q *Quantile) Push() []internal.Metric {
+	var out []internal.Metric
+	for _, id := range q.order {
+		s := q.series[id]
+		fields := make(map[string]interface{})
+		for _, key := range s.order {
+			d := s.digests[key]
+			for i, quantile := range q.Quantiles {
+				fields[key+q.suffixes[i]] = d.quantile(quantile)
+			}
+		}
+
+		m, err := metric.New(s.name, s.tags, fields, time.Time{}, internal.Summary)
+		if err != nil {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func (q *Quantile) Reset() {
+	q.series = make(map[uint64]*series)
+	q.order = nil
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func init() {
+	aggregators.Add("quantile", func() plugins.Aggregator {
+		return &Quantile{
+			Quantiles:   []float64{0.5, 0.9, 0.99},
+			Compression: 100,
+		}
+	})
+}
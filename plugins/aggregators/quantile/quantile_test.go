@@ -0,0 +1,72 @@
+package quantile
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigest(t *testing.T) {
+	d := newDigest(100)
+	require.True(t, math.IsNaN(d.quantile(0.5)))
+
+	r := rand.New(rand.NewSource(1))
+	for _, i := range r.Perm(100000) {
+		d.add(float64(i))
+	}
+	require.True(t, len(d.centroids) < 200)
+
+	for _, q := range []float64{0.01, 0.5, 0.9, 0.99, 0.999} {
+		require.InDelta(t, q*100000, d.quantile(q), 100000*0.002, "q=%v", q)
+	}
+	require.Equal(t, 0.0, d.quantile(0))
+	require.Equal(t, 99999.0, d.quantile(1))
+}
+
+func TestDigestSmall(t *testing.T) {
+	d := newDigest(100)
+	for _, x := range []float64{3, 1, 2} {
+		d.add(x)
+	}
+	require.Equal(t, 2.0, d.quantile(0.5))
+	require.Equal(t, 1.0, d.quantile(0))
+	require.Equal(t, 3.0, d.quantile(1))
+}
+
+func TestQuantile(t *testing.T) {
+	q := &Quantile{
+		Quantiles:   []float64{0.5, 0.999},
+		Fields:      []string{"response_*"},
+		Compression: 100,
+	}
+	require.NoError(t, q.Init())
+
+	tags := map[string]string{"server": "web01"}
+	for i := 1; i <= 101; i++ {
+		q.Add(testutil.MustMetric("http", tags,
+			map[string]interface{}{"response_time": int64(i), "status": int64(200)},
+			time.Unix(int64(i), 0)))
+	}
+
+	out := q.Push()
+	require.Len(t, out, 1)
+	require.Equal(t, internal.Summary, out[0].Type())
+	require.Equal(t, tags, out[0].Tags())
+	fields := out[0].Fields()
+	require.Len(t, fields, 2)
+	require.Equal(t, 51.0, fields["response_time_p50"])
+	require.InDelta(t, 101.0, fields["response_time_p99.9"], 0.5)
+
+	q.Reset()
+	require.Len(t, q.Push(), 0)
+}
+
+func TestInvalidQuantile(t *testing.T) {
+	q := &Quantile{Quantiles: []float64{1.5}, Compression: 100}
+	require.Error(t, q.Init())
+}
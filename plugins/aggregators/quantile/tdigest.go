@@ -0,0 +1,120 @@
+package quantile
+
+import (
+	"math"
+	"sort"
+)
+
+// centroid is the mean of count values.
+type centroid struct {
+	mean  float64
+	count float64
+}
+
+// digest is a merging t-digest, a sketch of a distribution that estimates
+// quantiles with an error that is smallest near the extremes.  Values are
+// buffered and merged into centroids whose size is bounded by the arcsine
+// scale function of the compression.
+type digest struct {
+	compression float64
+	centroids   []centroid
+	buffer      []centroid
+	total       float64
+	min, max    float64
+}
+
+func newDigest(compression float64) *digest {
+	return &digest{
+		compression: compression,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+func (d *digest) add(x float64) {
+	d.buffer = append(d.buffer, centroid{mean: x, count: 1})
+	d.total++
+	d.min = math.Min(d.min, x)
+	d.max = math.Max(d.max, x)
+	if len(d.buffer) >= int(5*d.compression) {
+		d.merge()
+	}
+}
+
+// k is the scale function, mapping a quantile to an index of which each
+// centroid may span at most one unit.
+func (d *digest) k(q float64) float64 {
+	return d.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+// q is the inverse of k.
+func (d *digest) q(k float64) float64 {
+	if k >= d.compression/4 {
+		return 1
+	}
+	return (math.Sin(k*2*math.Pi/d.compression) + 1) / 2
+}
+
+// merge merges the buffered values into the centroids.
+func (d *digest) merge() {
+	if len(d.buffer) == 0 {
+		return
+	}
+
+	all := append(d.centroids, d.buffer...)
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+
+	merged := make([]centroid, 0, len(d.centroids)+1)
+	cur := all[0]
+	before := 0.0
+	limit := d.total * d.q(d.k(0)+1)
+	for _, c := range all[1:] {
+		if before+cur.count+c.count <= limit {
+			cur.count += c.count
+			cur.mean += (c.mean - cur.mean) * c.count / cur.count
+			continue
+		}
+		merged = append(merged, cur)
+		before += cur.count
+		limit = d.total * d.q(d.k(before/d.total)+1)
+		cur = c
+	}
+	d.centroids = append(merged, cur)
+	d.buffer = nil
+}
+
+// quantile returns an estimate of the q quantile, interpolating between the
+// centers of the centroids, or NaN if no values were added.
+func (d *digest) quantile(q float64) float64 {
+	d.merge()
+	if len(d.centroids) == 0 {
+		return math.NaN()
+	}
+
+	target := q * d.total
+	first, last := d.centroids[0], d.centroids[len(d.centroids)-1]
+	if target <= first.count/2 {
+		if first.count == 1 {
+			return first.mean
+		}
+		return d.min + (first.mean-d.min)*target/(first.count/2)
+	}
+	if target >= d.total-last.count/2 {
+		if last.count == 1 {
+			return last.mean
+		}
+		return last.mean + (d.max-last.mean)*(target-(d.total-last.count/2))/(last.count/2)
+	}
+
+	// The target lies between the centers of two adjacent centroids.
+	center := first.count / 2
+	for i := 1; i < len(d.centroids); i++ {
+		prev, c := d.centroids[i-1], d.centroids[i]
+		next := center + (prev.count+c.count)/2
+		if target <= next {
+			return prev.mean + (c.mean-prev.mean)*(target-center)/(next-center)
+		}
+		center = next
+	}
+	return last.mean
+}
@@ -0,0 +1,11 @@
+package aggregators
+
+import "github.com/geekflow/straw/plugins"
+
+type Creator func() plugins.Aggregator
+
+var Aggregators = map[string]Creator{}
+
+func Add(name string, creator Creator) {
+	Aggregators[name] = creator
+}
@@ -0,0 +1,207 @@
+package topk
+
+import (
+	"fmt"
+	"github.com/geekflow/straw/filter"
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/aggregators"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var sampleConfig = `
+  ## Length of the periods over which the groups are ranked.  The metrics
+  ## of the top groups are emitted at the end of each period.
+  period = "10s"
+
+  ## Drop the original metrics, so that only the metrics of the top groups
+  ## are passed on.
+  drop_original = true
+
+  ## Number of groups to pass on each period.
+  k = 10
+
+  ## Tags that identify a group, glob patterns are supported.  By default
+  ## each series is its own group.
+  group_by = ["process_name"]
+
+  ## Field to rank the groups by.  Metrics without this field are emitted
+  ## unchanged.
+  field = "cpu_percent"
+
+  ## How the field values of a group are combined, "mean", "max" or "sum".
+  # aggregation = "mean"
+
+  ## Name of a tag to add with the rank of the group, starting at 1.
+  # rank_tag = ""
+`
+
+// TopK emits the metrics of the K groups with the highest aggregate value
+// of a field in each period.
+type TopK struct {
+	K           int      `toml:"k"`
+	GroupBy     []string `toml:"group_by"`
+	Field       string   `toml:"field"`
+	Aggregation string   `toml:"aggregation"`
+	RankTag     string   `toml:"rank_tag"`
+
+	groupBy filter.Filter
+	groups  map[string]*group
+	// others are the metrics without the field.
+	others []internal.Metric
+}
+
+// group holds the metrics of a group within the current period.
+type group struct {
+	key     string
+	metrics []internal.Metric
+	sum     float64
+	max     float64
+	count   int
+}
+
+func (*TopK) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*TopK) Description() string {
+	return "Emit the metrics of the top k groups of each period"
+}
+
+func (t *TopK) Init() error {
+	if t.K <= 0 {
+		return fmt.Errorf("topk: k must be positive")
+	}
+	if t.Field == "" {
+		return fmt.Errorf("topk: missing field")
+	}
+
+	switch t.Aggregation {
+	case "":
+		t.Aggregation = "mean"
+	case "mean", "max", "sum":
+	default:
+		return fmt.Errorf("topk: invalid aggregation %q", t.Aggregation)
+	}
+
+	var err error
+	t.groupBy, err = filter.Compile(t.GroupBy)
+	if err != nil {
+		return fmt.Errorf("topk: %v", err)
+	}
+
+	t.Reset()
+	return nil
+}
+
+func (t *TopK) Add(in internal.Metric) {
+	v, ok := in.GetField(t.Field)
+	if !ok {
+		t.others = append(t.others, in.Copy())
+		return
+	}
+	value, ok := toFloat(v)
+	if !ok {
+		t.others = append(t.others, in.Copy())
+		return
+	}
+
+	key := t.groupKey(in)
+	g, ok := t.groups[key]
+	if !ok {
+		g = &group{key: key, max: value}
+		t.groups[key] = g
+	}
+	g.metrics = append(g.metrics, in.Copy())
+	g.sum += value
+	g.count++
+	if value > g.max {
+		g.max = value
+	}
+}
+
+// Push returns the metrics of the top groups of the period and the metrics
+// without the field.
+func (t *TopK) Push() []internal.Metric {
+	groups := make([]*group, 0, len(t.groups))
+	for _, g := range t.groups {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := t.aggregate(groups[i]), t.aggregate(groups[j])
+		if a != b {
+			return a > b
+		}
+		return groups[i].key < groups[j].key
+	})
+	if len(groups) > t.K {
+		groups = groups[:t.K]
+	}
+
+	var out []internal.Metric
+	for rank, g := range groups {
+		for _, m := range g.metrics {
+			if t.RankTag != "" {
+				m.AddTag(t.RankTag, strconv.Itoa(rank+1))
+			}
+			out = append(out, m)
+		}
+	}
+	return append(out, t.others...)
+}
+
+func (t *TopK) Reset() {
+	t.groups = make(map[string]*group)
+	t.others = nil
+}
+
+func (t *TopK) aggregate(g *group) float64 {
+	switch t.Aggregation {
+	case "max":
+		return g.max
+	case "sum":
+		return g.sum
+	default:
+		return g.sum / float64(g.count)
+	}
+}
+
+// groupKey returns the measurement name and the group_by tags of the
+// metric.
+func (t *TopK) groupKey(m internal.Metric) string {
+	var b strings.Builder
+	b.WriteString(m.Name())
+	for _, tag := range m.TagList() {
+		if t.groupBy != nil && !t.groupBy.Match(tag.Key) {
+			continue
+		}
+		b.WriteByte(0)
+		b.WriteString(tag.Key)
+		b.WriteByte(0)
+		b.WriteString(tag.Value)
+	}
+	return b.String()
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func init() {
+	aggregators.Add("topk", func() plugins.Aggregator {
+		return &TopK{
+			K:           10,
+			Aggregation: "mean",
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package topk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func process(name string, pid int64, cpu float64, sec int64) internal.Metric {
+	return testutil.MustMetric("processes",
+		map[string]string{"name": name},
+		map[string]interface{}{"pid": pid, "cpu_percent": cpu},
+		time.Unix(sec, 0))
+}
+
+func names(metrics []internal.Metric) []string {
+	var out []string
+	for _, m := range metrics {
+		name, _ := m.GetTag("name")
+		out = append(out, name)
+	}
+	return out
+}
+
+func TestTopK(t *testing.T) {
+	k := &TopK{
+		K:       2,
+		GroupBy: []string{"name"},
+		Field:   "cpu_percent",
+		RankTag: "rank",
+	}
+	require.NoError(t, k.Init())
+
+	in := []internal.Metric{
+		process("nginx", 1, 10, 0),
+		process("nginx", 2, 30, 0),
+		process("postgres", 3, 15, 0),
+		process("sshd", 4, 40, 0),
+		process("cron", 5, 1, 0),
+	}
+	for _, m := range in {
+		k.Add(m)
+	}
+
+	out := k.Push()
+	require.Equal(t, []string{"sshd", "nginx", "nginx"}, names(out))
+	rank, _ := out[0].GetTag("rank")
+	require.Equal(t, "1", rank)
+	rank, _ = out[2].GetTag("rank")
+	require.Equal(t, "2", rank)
+	require.Equal(t, time.Unix(0, 0), out[0].Time())
+
+	// The metrics added are not modified.
+	require.False(t, in[3].HasTag("rank"))
+
+	k.Reset()
+	require.Len(t, k.Push(), 0)
+}
+
+func TestTopKAggregation(t *testing.T) {
+	in := []internal.Metric{
+		process("nginx", 1, 10, 0),
+		process("nginx", 2, 30, 0),
+		process("sshd", 4, 25, 0),
+	}
+
+	for aggregation, top := range map[string]string{"mean": "sshd", "max": "nginx", "sum": "nginx"} {
+		k := &TopK{
+			K:           1,
+			GroupBy:     []string{"name"},
+			Field:       "cpu_percent",
+			Aggregation: aggregation,
+		}
+		require.NoError(t, k.Init())
+
+		for _, m := range in {
+			k.Add(m)
+		}
+		out := k.Push()
+		require.NotEmpty(t, out, aggregation)
+		require.Equal(t, top, names(out)[0], aggregation)
+	}
+}
+
+func TestTopKPassesOtherMetrics(t *testing.T) {
+	k := &TopK{
+		K:     1,
+		Field: "cpu_percent",
+	}
+	require.NoError(t, k.Init())
+
+	other := testutil.MustMetric("mem",
+		map[string]string{},
+		map[string]interface{}{"used": uint64(1)},
+		time.Unix(0, 0))
+	k.Add(process("nginx", 1, 10, 0))
+	k.Add(process("sshd", 2, 5, 0))
+	k.Add(other)
+
+	out := k.Push()
+	require.Equal(t, []string{"nginx", ""}, names(out))
+	require.Equal(t, "mem", out[1].Name())
+
+	k = &TopK{K: 1}
+	require.Error(t, k.Init())
+}
@@ -0,0 +1,115 @@
+package valuecounter
+
+import (
+	"fmt"
+	"github.com/geekflow/straw/filter"
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/metric"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/aggregators"
+	"time"
+)
+
+var sampleConfig = `
+  ## Length of the periods over which the values are counted.
+  period = "30s"
+
+  ## Drop the original metrics instead of passing them on.
+  drop_original = false
+
+  ## Fields whose values are counted, glob patterns are supported.  Each
+  ## distinct value gives a field "<field>_<value>" with its count.
+  fields = ["status"]
+`
+
+// ValueCounter counts the occurrences of each distinct value of fields over
+// each period.
+type ValueCounter struct {
+	Fields []string `toml:"fields"`
+
+	fields filter.Filter
+	series map[uint64]*series
+	// order is the order in which the series were first seen.
+	order []uint64
+}
+
+// series holds the counts of a series within the current period, by output
+// field name.
+type series struct {
+	name   string
+	tags   map[string]string
+	counts map[string]int64
+}
+
+func (*ValueCounter) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*ValueCounter) Description() string {
+	return "Count the occurrences of each value of fields over each period"
+}
+
+func (v *ValueCounter) Init() error {
+	if len(v.Fields) == 0 {
+		return fmt.Errorf("valuecounter: no fields")
+	}
+
+	var err error
+	v.fields, err = filter.Compile(v.Fields)
+	if err != nil {
+		return fmt.Errorf("valuecounter: %v", err)
+	}
+
+	v.Reset()
+	return nil
+}
+
+func (v *ValueCounter) Add(in internal.Metric) {
+	id := in.HashID()
+	for _, field := range in.FieldList() {
+		if !v.fields.Match(field.Key) {
+			continue
+		}
+
+		s, ok := v.series[id]
+		if !ok {
+			s = &series{
+				name:   in.Name(),
+				tags:   in.Tags(),
+				counts: make(map[string]int64),
+			}
+			v.series[id] = s
+			v.order = append(v.order, id)
+		}
+		s.counts[fmt.Sprintf("%s_%v", field.Key, field.Value)]++
+	}
+}
+
+func (v *ValueCounter) Push() []internal.Metric {
+	out := make([]internal.Metric, 0, len(v.order))
+	for _, id := range v.order {
+		s := v.series[id]
+		fields := make(map[string]interface{}, len(s.counts))
+		for key, count := range s.counts {
+			fields[key] = count
+		}
+
+		m, err := metric.New(s.name, s.tags, fields, time.Time{})
+		if err != nil {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func (v *ValueCounter) Reset() {
+	v.series = make(map[uint64]*series)
+	v.order = nil
+}
+
+func init() {
+	aggregators.Add("valuecounter", func() plugins.Aggregator {
+		return &ValueCounter{}
+	})
+}
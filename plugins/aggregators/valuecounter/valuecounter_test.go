@@ -0,0 +1,42 @@
+package valuecounter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueCounter(t *testing.T) {
+	v := &ValueCounter{Fields: []string{"status", "result"}}
+	require.NoError(t, v.Init())
+
+	add := func(tags map[string]string, fields map[string]interface{}) {
+		v.Add(testutil.MustMetric("process", tags, fields, time.Unix(0, 0)))
+	}
+	web := map[string]string{"host": "web01"}
+	add(web, map[string]interface{}{"status": "S", "cpu": 1.0})
+	add(web, map[string]interface{}{"status": "S"})
+	add(web, map[string]interface{}{"status": "R", "result": true})
+	add(map[string]string{"host": "web02"}, map[string]interface{}{"status": "Z"})
+	add(map[string]string{"host": "web03"}, map[string]interface{}{"cpu": 1.0})
+
+	out := v.Push()
+	require.Len(t, out, 2)
+	require.Equal(t, web, out[0].Tags())
+	require.True(t, out[0].Time().IsZero())
+	require.Equal(t, map[string]interface{}{
+		"status_S":    int64(2),
+		"status_R":    int64(1),
+		"result_true": int64(1),
+	}, out[0].Fields())
+	require.Equal(t, map[string]interface{}{"status_Z": int64(1)}, out[1].Fields())
+
+	v.Reset()
+	require.Len(t, v.Push(), 0)
+}
+
+func TestNoFields(t *testing.T) {
+	require.Error(t, (&ValueCounter{}).Init())
+}
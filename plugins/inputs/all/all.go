@@ -1,10 +1,18 @@
 package all
 
 import (
+	_ "github.com/geekflow/straw/plugins/inputs/cgroup"
 	_ "github.com/geekflow/straw/plugins/inputs/cpu"
 	_ "github.com/geekflow/straw/plugins/inputs/disk"
+	_ "github.com/geekflow/straw/plugins/inputs/diskio"
+	_ "github.com/geekflow/straw/plugins/inputs/internal"
+	_ "github.com/geekflow/straw/plugins/inputs/kernel"
 	_ "github.com/geekflow/straw/plugins/inputs/mem"
 	_ "github.com/geekflow/straw/plugins/inputs/net"
 	_ "github.com/geekflow/straw/plugins/inputs/process"
+	_ "github.com/geekflow/straw/plugins/inputs/processes"
 	_ "github.com/geekflow/straw/plugins/inputs/procstat"
+	_ "github.com/geekflow/straw/plugins/inputs/swap"
+	_ "github.com/geekflow/straw/plugins/inputs/system"
+	_ "github.com/geekflow/straw/plugins/inputs/temp"
 )
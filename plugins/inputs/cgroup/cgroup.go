@@ -0,0 +1,259 @@
+package cgroup
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/geekflow/straw/filter"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/inputs"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var sampleConfig = `
+  ## Directories of the cgroups to report, glob patterns are supported.  Both
+  ## cgroup v2 directories and v1 controller directories may be given.
+  paths = [
+    "/sys/fs/cgroup/system.slice/*.service",
+    "/sys/fs/cgroup/memory/docker/*",
+  ]
+
+  ## Files to read, glob patterns are supported.  By default all supported
+  ## files present in a directory are read.
+  # files = ["cpu.stat", "memory.*"]
+`
+
+// Cgroup reports the resource accounting of cgroups.
+type Cgroup struct {
+	Paths []string `toml:"paths"`
+	Files []string `toml:"files"`
+
+	files filter.Filter
+}
+
+// format is the format of a cgroup file.
+type format int
+
+const (
+	// singleValue files hold one value.
+	singleValue format = iota
+	// flatKeyed files have a "key value" line per value.
+	flatKeyed
+	// nestedKeyed files have a "key subkey=value ..." line per key.
+	nestedKeyed
+	// deviceKeyed files have a "major:minor key=value ..." line per
+	// device, reported as a metric per device.
+	deviceKeyed
+	// deviceOps files have a "major:minor op value" line per device and
+	// operation, reported as a metric per device.
+	deviceOps
+)
+
+// files are the supported cgroup v2 and v1 files and their formats.
+var files = []struct {
+	name   string
+	format format
+}{
+	{"cpu.stat", flatKeyed},
+	{"cpu.pressure", nestedKeyed},
+	{"cpuacct.usage", singleValue},
+	{"memory.current", singleValue},
+	{"memory.max", singleValue},
+	{"memory.swap.current", singleValue},
+	{"memory.stat", flatKeyed},
+	{"memory.pressure", nestedKeyed},
+	{"memory.usage_in_bytes", singleValue},
+	{"memory.max_usage_in_bytes", singleValue},
+	{"memory.limit_in_bytes", singleValue},
+	{"memory.failcnt", singleValue},
+	{"io.stat", deviceKeyed},
+	{"io.pressure", nestedKeyed},
+	{"blkio.throttle.io_service_bytes", deviceOps},
+	{"blkio.throttle.io_serviced", deviceOps},
+	{"pids.current", singleValue},
+	{"pids.max", singleValue},
+}
+
+func (*Cgroup) Description() string {
+	return "Read resource accounting of cgroups"
+}
+
+func (*Cgroup) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Cgroup) Init() error {
+	if len(c.Paths) == 0 {
+		return fmt.Errorf("cgroup: no paths")
+	}
+	for _, path := range c.Paths {
+		if _, err := filepath.Match(path, ""); err != nil {
+			return fmt.Errorf("cgroup: %q: %v", path, err)
+		}
+	}
+
+	var err error
+	c.files, err = filter.Compile(c.Files)
+	if err != nil {
+		return fmt.Errorf("cgroup: %v", err)
+	}
+	return nil
+}
+
+func (c *Cgroup) Gather(acc plugins.Accumulator) error {
+	for _, dir := range c.directories() {
+		c.gatherDir(acc, dir)
+	}
+	return nil
+}
+
+// directories returns the directories matching the paths, each once.
+func (c *Cgroup) directories() []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, pattern := range c.Paths {
+		matches, _ := filepath.Glob(pattern)
+		sort.Strings(matches)
+		for _, path := range matches {
+			if seen[path] {
+				continue
+			}
+			if info, err := os.Stat(path); err != nil || !info.IsDir() {
+				continue
+			}
+			seen[path] = true
+			dirs = append(dirs, path)
+		}
+	}
+	return dirs
+}
+
+func (c *Cgroup) gatherDir(acc plugins.Accumulator, dir string) {
+	fields := make(map[string]interface{})
+	devices := make(map[string]map[string]interface{})
+
+	for _, file := range files {
+		if c.files != nil && !c.files.Match(file.name) {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, file.name))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				acc.AddError(fmt.Errorf("cgroup %s: %v", dir, err))
+			}
+			continue
+		}
+
+		prefix := strings.Replace(file.name, ".", "_", -1)
+		switch file.format {
+		case singleValue:
+			// Limits may be "max" for no limit.
+			if v, ok := parseValue(strings.TrimSpace(string(data))); ok {
+				fields[prefix] = v
+			}
+		case flatKeyed:
+			eachLine(data, func(values []string) {
+				if len(values) == 2 {
+					addValue(fields, prefix+"_"+values[0], values[1])
+				}
+			})
+		case nestedKeyed:
+			eachLine(data, func(values []string) {
+				addKeyed(fields, prefix+"_"+values[0], values[1:])
+			})
+		case deviceKeyed:
+			eachLine(data, func(values []string) {
+				addKeyed(deviceFields(devices, values[0]), prefix, values[1:])
+			})
+		case deviceOps:
+			eachLine(data, func(values []string) {
+				if len(values) == 3 {
+					addValue(deviceFields(devices, values[0]),
+						prefix+"_"+strings.ToLower(values[1]), values[2])
+				}
+			})
+		}
+	}
+
+	if len(fields) > 0 {
+		acc.AddFields("cgroup", fields, map[string]string{"path": dir})
+	}
+
+	names := make([]string, 0, len(devices))
+	for device := range devices {
+		names = append(names, device)
+	}
+	sort.Strings(names)
+	for _, device := range names {
+		acc.AddFields("cgroup", devices[device], map[string]string{"path": dir, "device": device})
+	}
+}
+
+// eachLine calls f with the space separated values of each line of data
+// that has at least two values.
+func eachLine(data []byte, f func(values []string)) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		values := strings.Fields(scanner.Text())
+		if len(values) >= 2 {
+			f(values)
+		}
+	}
+}
+
+// addKeyed adds the "key=value" pairs as fields named prefix_key.
+func addKeyed(fields map[string]interface{}, prefix string, pairs []string) {
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) == 2 {
+			addValue(fields, prefix+"_"+kv[0], kv[1])
+		}
+	}
+}
+
+func addValue(fields map[string]interface{}, key, s string) {
+	if v, ok := parseValue(s); ok {
+		fields[key] = v
+	}
+}
+
+// deviceFields returns the fields of a device, only "major:minor" devices
+// are reported; blkio files also have a Total line.
+func deviceFields(devices map[string]map[string]interface{}, device string) map[string]interface{} {
+	if !strings.Contains(device, ":") {
+		return make(map[string]interface{})
+	}
+	fields, ok := devices[device]
+	if !ok {
+		fields = make(map[string]interface{})
+		devices[device] = fields
+	}
+	return fields
+}
+
+// parseValue parses an integer or a finite float.
+func parseValue(s string) (interface{}, bool) {
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return v, true
+	}
+	if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return v, true
+	}
+	if v, err := strconv.ParseFloat(s, 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+		return v, true
+	}
+	return nil, false
+}
+
+func init() {
+	inputs.Add("cgroup", func() plugins.Input {
+		return &Cgroup{}
+	})
+}
@@ -0,0 +1,63 @@
+package cgroup
+
+import (
+	"testing"
+
+	"github.com/geekflow/straw/internal/config"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCgroupV2(t *testing.T) {
+	c := &Cgroup{Paths: []string{"testdata/v2/system.slice/*.service"}}
+	require.NoError(t, c.Init())
+
+	h := testutil.NewInputHarness(c)
+	h.RequireGolden(t, "testdata/v2.golden")
+}
+
+func TestCgroupV1(t *testing.T) {
+	c := &Cgroup{Paths: []string{"testdata/v1/*/docker/*"}}
+	require.NoError(t, c.Init())
+
+	h := testutil.NewInputHarness(c)
+	h.RequireGolden(t, "testdata/v1.golden")
+}
+
+func TestCgroupFiles(t *testing.T) {
+	c := &Cgroup{
+		Paths: []string{"testdata/v2/system.slice/nginx.service", "testdata/v2/*/nginx.service"},
+		Files: []string{"cpu.*"},
+	}
+	require.NoError(t, c.Init())
+
+	h := testutil.NewInputHarness(c)
+	metrics := h.Gather(t)
+	require.Len(t, metrics, 1)
+	require.Equal(t, "testdata/v2/system.slice/nginx.service", metrics[0].Tags()["path"])
+	require.Equal(t, int64(37), metrics[0].Fields()["cpu_stat_nr_throttled"])
+	require.Equal(t, 1.25, metrics[0].Fields()["cpu_pressure_some_avg10"])
+	require.NotContains(t, metrics[0].Fields(), "memory_current")
+}
+
+func TestCgroupInit(t *testing.T) {
+	require.Error(t, (&Cgroup{}).Init())
+	require.Error(t, (&Cgroup{Paths: []string{"["}}).Init())
+}
+
+func TestCgroupConfig(t *testing.T) {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadConfig("testdata/cgroup.toml"))
+	require.Len(t, c.Inputs, 2)
+
+	// The agent initializes the inputs through the RunningInput.
+	require.NoError(t, c.Inputs[0].Init())
+	require.Error(t, c.Inputs[1].Init())
+
+	h := testutil.NewInputHarness(c.Inputs[0].Input)
+	metrics := h.Gather(t)
+	require.Len(t, metrics, 1)
+	require.Equal(t, int64(104857600), metrics[0].Fields()["memory_current"])
+	require.NotContains(t, metrics[0].Fields(), "cpu_stat_nr_throttled")
+	require.NotContains(t, metrics[0].Fields(), "pids_current")
+}
@@ -11,20 +11,20 @@ import (
 )
 
 type CPUStats struct {
-	ps        system.PS
-	lastStats map[string]cpu.TimesStat
+	ps system.PS
 
-	PerCPU         bool `toml:"percpu"`
-	TotalCPU       bool `toml:"totalcpu"`
+	PerCPU   bool `toml:"percpu"`
+	TotalCPU bool `toml:"totalcpu"`
+	// CollectCPUTime is deprecated; the CPU time counters are always
+	// reported and the usage is computed by the rate processor.
 	CollectCPUTime bool `toml:"collect_cpu_time"`
 	ReportActive   bool `toml:"report_active"`
 }
 
 func NewCPUStats(ps system.PS) *CPUStats {
 	return &CPUStats{
-		ps:             ps,
-		CollectCPUTime: true,
-		ReportActive:   true,
+		ps:           ps,
+		ReportActive: true,
 	}
 }
 
@@ -37,16 +37,26 @@ var sampleConfig = `
   percpu = true
   ## Whether to report total system cpu stats or not
   totalcpu = true
-  ## If true, collect raw CPU time metrics.
-  collect_cpu_time = false
-  ## If true, compute and report the sum of all non-idle CPU states.
+  ## If true, report the sum of all non-idle CPU states.
   report_active = false
+
+  ## The input reports the CPU time counters, the usage percentages are
+  ## computed from them by the rate processor.
+  [[inputs.cpu.processors.rate]]
+    fields = ["time_*"]
+    mode = "percent"
+    total_field = "time_total"
+    trim_prefix = "time_"
+    prefix = "usage_"
 `
 
 func (_ *CPUStats) SampleConfig() string {
 	return sampleConfig
 }
 
+// Gather reports the CPU time counters.  The time spent running guests is
+// already part of user and nice, it is left out of time_user and time_nice
+// so that the percentages computed from them add up to 100.
 func (s *CPUStats) Gather(acc plugins.Accumulator) error {
 	times, err := s.ps.CPUTimes(s.PerCPU, s.TotalCPU)
 	if err != nil {
@@ -59,76 +69,25 @@ func (s *CPUStats) Gather(acc plugins.Accumulator) error {
 			"cpu": cts.CPU,
 		}
 
-		total := totalCpuTime(cts)
-		active := activeCpuTime(cts)
-
-		if s.CollectCPUTime {
-			// Add cpu time metrics
-			fieldsC := map[string]interface{}{
-				"time_user":       cts.User,
-				"time_system":     cts.System,
-				"time_idle":       cts.Idle,
-				"time_nice":       cts.Nice,
-				"time_iowait":     cts.Iowait,
-				"time_irq":        cts.Irq,
-				"time_softirq":    cts.Softirq,
-				"time_steal":      cts.Steal,
-				"time_guest":      cts.Guest,
-				"time_guest_nice": cts.GuestNice,
-			}
-			if s.ReportActive {
-				fieldsC["time_active"] = activeCpuTime(cts)
-			}
-			acc.AddCounter("cpu", fieldsC, tags, now)
-		}
-
-		// Add in percentage
-		if len(s.lastStats) == 0 {
-			// If it's the 1st gather, can't get CPU Usage stats yet
-			continue
-		}
-
-		lastCts, ok := s.lastStats[cts.CPU]
-		if !ok {
-			continue
-		}
-		lastTotal := totalCpuTime(lastCts)
-		lastActive := activeCpuTime(lastCts)
-		totalDelta := total - lastTotal
-
-		if totalDelta < 0 {
-			err = fmt.Errorf("Error: current total CPU time is less than previous total CPU time")
-			break
-		}
-
-		if totalDelta == 0 {
-			continue
-		}
-
-		fieldsG := map[string]interface{}{
-			"usage_user":       100 * (cts.User - lastCts.User - (cts.Guest - lastCts.Guest)) / totalDelta,
-			"usage_system":     100 * (cts.System - lastCts.System) / totalDelta,
-			"usage_idle":       100 * (cts.Idle - lastCts.Idle) / totalDelta,
-			"usage_nice":       100 * (cts.Nice - lastCts.Nice - (cts.GuestNice - lastCts.GuestNice)) / totalDelta,
-			"usage_iowait":     100 * (cts.Iowait - lastCts.Iowait) / totalDelta,
-			"usage_irq":        100 * (cts.Irq - lastCts.Irq) / totalDelta,
-			"usage_softirq":    100 * (cts.Softirq - lastCts.Softirq) / totalDelta,
-			"usage_steal":      100 * (cts.Steal - lastCts.Steal) / totalDelta,
-			"usage_guest":      100 * (cts.Guest - lastCts.Guest) / totalDelta,
-			"usage_guest_nice": 100 * (cts.GuestNice - lastCts.GuestNice) / totalDelta,
+		fields := map[string]interface{}{
+			"time_user":       cts.User - cts.Guest,
+			"time_system":     cts.System,
+			"time_idle":       cts.Idle,
+			"time_nice":       cts.Nice - cts.GuestNice,
+			"time_iowait":     cts.Iowait,
+			"time_irq":        cts.Irq,
+			"time_softirq":    cts.Softirq,
+			"time_steal":      cts.Steal,
+			"time_guest":      cts.Guest,
+			"time_guest_nice": cts.GuestNice,
+			"time_total":      totalCpuTime(cts),
 		}
 		if s.ReportActive {
-			fieldsG["usage_active"] = 100 * (active - lastActive) / totalDelta
+			fields["time_active"] = activeCpuTime(cts)
 		}
-		acc.AddGauge("cpu", fieldsG, tags, now)
+		acc.AddCounter("cpu", fields, tags, now)
 	}
-
-	s.lastStats = make(map[string]cpu.TimesStat)
-	for _, cts := range times {
-		s.lastStats[cts.CPU] = cts
-	}
-
-	return err
+	return nil
 }
 
 func totalCpuTime(t cpu.TimesStat) float64 {
@@ -2,7 +2,9 @@ package cpu
 
 import (
 	"fmt"
+	"github.com/geekflow/straw/internal"
 	"github.com/geekflow/straw/plugins/inputs/system"
+	"github.com/geekflow/straw/plugins/processors/rate"
 	"github.com/geekflow/straw/testutil"
 	"testing"
 
@@ -56,50 +58,74 @@ func TestCPUStats(t *testing.T) {
 	require.NoError(t, err)
 
 	// Computed values are checked with delta > 0 because of floating point arithmatic
-	// imprecision
-	assertContainsTaggedFloat(t, &acc, "cpu", "time_user", 8.8, 0, cputags)
+	// imprecision.  Guest time is left out of the user and nice counters.
+	assertContainsTaggedFloat(t, &acc, "cpu", "time_user", 5.7, 0.0005, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_system", 8.2, 0, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_idle", 80.1, 0, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_active", 19.9, 0.0005, cputags)
-	assertContainsTaggedFloat(t, &acc, "cpu", "time_nice", 1.3, 0, cputags)
+	assertContainsTaggedFloat(t, &acc, "cpu", "time_nice", 0.976, 0.0005, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_iowait", 0.8389, 0, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_irq", 0.6, 0, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_softirq", 0.11, 0, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_steal", 0.0511, 0, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_guest", 3.1, 0, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_guest_nice", 0.324, 0, cputags)
+	assertContainsTaggedFloat(t, &acc, "cpu", "time_total", 100, 0.0005, cputags)
 
 	mps2 := system.MockPS{}
 	mps2.On("CPUTimes").Return([]cpu.TimesStat{cts2}, nil)
 	cs.ps = &mps2
 
-	// Should have added cpu percentages too
 	err = cs.Gather(&acc)
 	require.NoError(t, err)
 
-	assertContainsTaggedFloat(t, &acc, "cpu", "time_user", 24.9, 0, cputags)
+	assertContainsTaggedFloat(t, &acc, "cpu", "time_user", 13.5, 0.0005, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_system", 10.9, 0, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_idle", 157.9798, 0, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_active", 42.0202, 0.0005, cputags)
-	assertContainsTaggedFloat(t, &acc, "cpu", "time_nice", 3.5, 0, cputags)
+	assertContainsTaggedFloat(t, &acc, "cpu", "time_nice", 0.976, 0.0005, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_iowait", 0.929, 0, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_irq", 1.2, 0, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_softirq", 0.31, 0, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_steal", 0.2812, 0, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_guest", 11.4, 0, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_guest_nice", 2.524, 0, cputags)
+	assertContainsTaggedFloat(t, &acc, "cpu", "time_total", 200, 0.0005, cputags)
+
+	// The input leaves the percentages to the rate processor.
+	require.False(t, acc.HasField("cpu", "usage_user"))
+
+	usage := usageMetrics(t, acc.GetTelegrafMetrics())
+	assertContainsTaggedFloat(t, usage, "cpu", "usage_user", 7.8, 0.0005, cputags)
+	assertContainsTaggedFloat(t, usage, "cpu", "usage_system", 2.7, 0.0005, cputags)
+	assertContainsTaggedFloat(t, usage, "cpu", "usage_idle", 77.8798, 0.0005, cputags)
+	assertContainsTaggedFloat(t, usage, "cpu", "usage_active", 22.1202, 0.0005, cputags)
+	assertContainsTaggedFloat(t, usage, "cpu", "usage_nice", 0, 0.0005, cputags)
+	assertContainsTaggedFloat(t, usage, "cpu", "usage_iowait", 0.0901, 0.0005, cputags)
+	assertContainsTaggedFloat(t, usage, "cpu", "usage_irq", 0.6, 0.0005, cputags)
+	assertContainsTaggedFloat(t, usage, "cpu", "usage_softirq", 0.2, 0.0005, cputags)
+	assertContainsTaggedFloat(t, usage, "cpu", "usage_steal", 0.2301, 0.0005, cputags)
+	assertContainsTaggedFloat(t, usage, "cpu", "usage_guest", 8.3, 0.0005, cputags)
+	assertContainsTaggedFloat(t, usage, "cpu", "usage_guest_nice", 2.2, 0.0005, cputags)
+}
+
+// usageMetrics applies the rate processor of the sample config to the
+// metrics and returns the results in an accumulator.
+func usageMetrics(t *testing.T, metrics []internal.Metric) *testutil.Accumulator {
+	r := &rate.Rate{
+		Fields:     []string{"time_*"},
+		Mode:       "percent",
+		TotalField: "time_total",
+		TrimPrefix: "time_",
+		Prefix:     "usage_",
+	}
+	require.NoError(t, r.Init())
 
-	assertContainsTaggedFloat(t, &acc, "cpu", "usage_user", 7.8, 0.0005, cputags)
-	assertContainsTaggedFloat(t, &acc, "cpu", "usage_system", 2.7, 0.0005, cputags)
-	assertContainsTaggedFloat(t, &acc, "cpu", "usage_idle", 77.8798, 0.0005, cputags)
-	assertContainsTaggedFloat(t, &acc, "cpu", "usage_active", 22.1202, 0.0005, cputags)
-	assertContainsTaggedFloat(t, &acc, "cpu", "usage_nice", 0, 0.0005, cputags)
-	assertContainsTaggedFloat(t, &acc, "cpu", "usage_iowait", 0.0901, 0.0005, cputags)
-	assertContainsTaggedFloat(t, &acc, "cpu", "usage_irq", 0.6, 0.0005, cputags)
-	assertContainsTaggedFloat(t, &acc, "cpu", "usage_softirq", 0.2, 0.0005, cputags)
-	assertContainsTaggedFloat(t, &acc, "cpu", "usage_steal", 0.2301, 0.0005, cputags)
-	assertContainsTaggedFloat(t, &acc, "cpu", "usage_guest", 8.3, 0.0005, cputags)
-	assertContainsTaggedFloat(t, &acc, "cpu", "usage_guest_nice", 2.2, 0.0005, cputags)
+	var acc testutil.Accumulator
+	for _, m := range metrics {
+		acc.AddMetrics(r.Apply(m))
+	}
+	return &acc
 }
 
 // Asserts that a given accumulator contains a measurment of type float64 with
@@ -235,9 +261,9 @@ func TestCPUTimesDecrease(t *testing.T) {
 	mps2.On("CPUTimes").Return([]cpu.TimesStat{cts2}, nil)
 	cs.ps = &mps2
 
-	// CPU times decreased. An error should be raised
+	// CPU times decreased, the rate processor skips the sample.
 	err = cs.Gather(&acc)
-	require.Error(t, err)
+	require.NoError(t, err)
 
 	mps3 := system.MockPS{}
 	mps3.On("CPUTimes").Return([]cpu.TimesStat{cts3}, nil)
@@ -250,7 +276,9 @@ func TestCPUTimesDecrease(t *testing.T) {
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_idle", 120, 0, cputags)
 	assertContainsTaggedFloat(t, &acc, "cpu", "time_iowait", 3, 0, cputags)
 
-	assertContainsTaggedFloat(t, &acc, "cpu", "usage_user", 18, 0.0005, cputags)
-	assertContainsTaggedFloat(t, &acc, "cpu", "usage_idle", 80, 0.0005, cputags)
-	assertContainsTaggedFloat(t, &acc, "cpu", "usage_iowait", 2, 0.0005, cputags)
+	usage := usageMetrics(t, acc.GetTelegrafMetrics())
+	require.Equal(t, uint64(1), usage.NMetrics())
+	assertContainsTaggedFloat(t, usage, "cpu", "usage_user", 18, 0.0005, cputags)
+	assertContainsTaggedFloat(t, usage, "cpu", "usage_idle", 80, 0.0005, cputags)
+	assertContainsTaggedFloat(t, usage, "cpu", "usage_iowait", 2, 0.0005, cputags)
 }
@@ -0,0 +1,205 @@
+package diskio
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/geekflow/straw/filter"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/inputs"
+	"github.com/geekflow/straw/plugins/inputs/system"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var sampleConfig = `
+  ## Devices to report, glob patterns are supported.  By default all
+  ## devices are reported.
+  # devices = ["sda", "sdb", "nvme*"]
+
+  ## udev properties of the devices to add as tags.
+  # device_tags = ["ID_FS_TYPE", "ID_FS_USAGE"]
+
+  ## Templates for the name tag, using udev properties of the device.  The
+  ## first template whose properties are all set is used, otherwise the
+  ## kernel name of the device.
+  # name_templates = ["$ID_FS_LABEL", "$DM_VG_NAME/$DM_LV_NAME"]
+
+  ## Directory of the udev database.
+  # udev_data_path = "/run/udev/data"
+`
+
+// DiskIO reports the I/O counters of block devices.
+type DiskIO struct {
+	ps system.PS
+
+	Devices       []string `toml:"devices"`
+	DeviceTags    []string `toml:"device_tags"`
+	NameTemplates []string `toml:"name_templates"`
+	UdevDataPath  string   `toml:"udev_data_path"`
+
+	deviceFilter filter.Filter
+	// udevDataFile returns the path of the udev data file of a device.
+	udevDataFile func(name string) (string, error)
+	infoCache    map[string]diskInfo
+}
+
+// diskInfo are the udev properties of a device, read from its data file at
+// its modification time.
+type diskInfo struct {
+	modTime int64
+	values  map[string]string
+}
+
+func (*DiskIO) Description() string {
+	return "Read metrics about disk IO by device"
+}
+
+func (*DiskIO) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *DiskIO) Init() error {
+	var err error
+	d.deviceFilter, err = filter.Compile(d.Devices)
+	if err != nil {
+		return fmt.Errorf("diskio: %v", err)
+	}
+
+	if d.udevDataFile == nil {
+		d.udevDataFile = func(name string) (string, error) {
+			return udevDataFile(d.UdevDataPath, name)
+		}
+	}
+	d.infoCache = make(map[string]diskInfo)
+	return nil
+}
+
+func (d *DiskIO) Gather(acc plugins.Accumulator) error {
+	counters, err := d.ps.DiskIO(nil)
+	if err != nil {
+		return fmt.Errorf("error getting disk io info: %s", err)
+	}
+
+	for _, io := range counters {
+		if d.deviceFilter != nil && !d.deviceFilter.Match(io.Name) {
+			continue
+		}
+
+		tags := map[string]string{"name": io.Name}
+		if len(d.DeviceTags) > 0 || len(d.NameTemplates) > 0 {
+			info, err := d.diskInfo(io.Name)
+			if err != nil {
+				log.Debugf("[inputs.diskio] %s: %v", io.Name, err)
+			}
+			tags["name"] = d.diskName(io.Name, info)
+			for _, key := range d.DeviceTags {
+				if v, ok := info[key]; ok {
+					tags[key] = v
+				}
+			}
+		}
+
+		fields := map[string]interface{}{
+			"reads":            io.ReadCount,
+			"writes":           io.WriteCount,
+			"read_bytes":       io.ReadBytes,
+			"write_bytes":      io.WriteBytes,
+			"read_time":        io.ReadTime,
+			"write_time":       io.WriteTime,
+			"io_time":          io.IoTime,
+			"weighted_io_time": io.WeightedIO,
+			"iops_in_progress": io.IopsInProgress,
+			"merged_reads":     io.MergedReadCount,
+			"merged_writes":    io.MergedWriteCount,
+		}
+		acc.AddCounter("diskio", fields, tags)
+	}
+
+	return nil
+}
+
+// diskName returns the name of the first template whose properties are all
+// set, or the kernel name of the device.
+func (d *DiskIO) diskName(devName string, info map[string]string) string {
+	for _, template := range d.NameTemplates {
+		missing := false
+		name := os.Expand(template, func(key string) string {
+			v, ok := info[key]
+			if !ok {
+				missing = true
+			}
+			return v
+		})
+		if !missing && name != "" {
+			return name
+		}
+	}
+	return devName
+}
+
+// diskInfo returns the udev properties of a device, reading its data file
+// again only when it has changed.
+func (d *DiskIO) diskInfo(devName string) (map[string]string, error) {
+	path, err := d.udevDataFile(devName)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info, ok := d.infoCache[devName]; ok && info.modTime == stat.ModTime().UnixNano() {
+		return info.values, nil
+	}
+
+	values, err := readUdevData(path)
+	if err != nil {
+		return nil, err
+	}
+	d.infoCache[devName] = diskInfo{modTime: stat.ModTime().UnixNano(), values: values}
+	return values, nil
+}
+
+// readUdevData reads the properties of a udev data file, from its "E:"
+// lines.  The symlinks of the "S:" lines are joined in DEVLINKS.
+func readUdevData(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	var devlinks []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "E:"):
+			kv := strings.SplitN(line[2:], "=", 2)
+			if len(kv) == 2 {
+				values[kv[0]] = kv[1]
+			}
+		case strings.HasPrefix(line, "S:"):
+			devlinks = append(devlinks, "/dev/"+line[2:])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(devlinks) > 0 {
+		values["DEVLINKS"] = strings.Join(devlinks, " ")
+	}
+	return values, nil
+}
+
+func init() {
+	ps := system.NewSystemPS()
+	inputs.Add("diskio", func() plugins.Input {
+		return &DiskIO{ps: ps, UdevDataPath: "/run/udev/data"}
+	})
+}
@@ -0,0 +1,21 @@
+package diskio
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// udevDataFile returns the path of the udev data file of a block device,
+// named after its major and minor numbers.
+func udevDataFile(udevDataPath, devName string) (string, error) {
+	var stat unix.Stat_t
+	if err := unix.Stat("/dev/"+devName, &stat); err != nil {
+		return "", err
+	}
+
+	major := unix.Major(uint64(stat.Rdev))
+	minor := unix.Minor(uint64(stat.Rdev))
+	return filepath.Join(udevDataPath, fmt.Sprintf("b%d:%d", major, minor)), nil
+}
@@ -0,0 +1,9 @@
+// +build !linux
+
+package diskio
+
+import "fmt"
+
+func udevDataFile(udevDataPath, devName string) (string, error) {
+	return "", fmt.Errorf("udev is only supported on linux")
+}
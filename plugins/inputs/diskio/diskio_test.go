@@ -0,0 +1,114 @@
+package diskio
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/geekflow/straw/internal/config"
+	"github.com/geekflow/straw/plugins/inputs/system"
+	"github.com/geekflow/straw/testutil"
+	"github.com/shirou/gopsutil/disk"
+	"github.com/stretchr/testify/require"
+)
+
+func counters() map[string]disk.IOCountersStat {
+	return map[string]disk.IOCountersStat{
+		"sda": {
+			Name:           "sda",
+			ReadCount:      888,
+			WriteCount:     5341,
+			ReadBytes:      100000,
+			WriteBytes:     200000,
+			ReadTime:       7123,
+			WriteTime:      9087,
+			IoTime:         123552,
+			WeightedIO:     12345,
+			IopsInProgress: 2,
+		},
+		"loop0": {Name: "loop0", ReadCount: 1},
+	}
+}
+
+// fixtureDataFile maps sda to the fixture and other devices to missing files.
+func fixtureDataFile(name string) (string, error) {
+	if name == "sda" {
+		return filepath.Join("testdata", "udev", "b8:0"), nil
+	}
+	return "", fmt.Errorf("no device %s", name)
+}
+
+func TestDiskIO(t *testing.T) {
+	var mps system.MockPS
+	defer mps.AssertExpectations(t)
+	mps.On("DiskIO").Return(counters(), nil)
+
+	d := &DiskIO{ps: &mps, Devices: []string{"sd*"}}
+	require.NoError(t, d.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	require.Equal(t, uint64(1), acc.NMetrics())
+	acc.AssertContainsTaggedFields(t, "diskio",
+		map[string]interface{}{
+			"reads":            uint64(888),
+			"writes":           uint64(5341),
+			"read_bytes":       uint64(100000),
+			"write_bytes":      uint64(200000),
+			"read_time":        uint64(7123),
+			"write_time":       uint64(9087),
+			"io_time":          uint64(123552),
+			"weighted_io_time": uint64(12345),
+			"iops_in_progress": uint64(2),
+			"merged_reads":     uint64(0),
+			"merged_writes":    uint64(0),
+		},
+		map[string]string{"name": "sda"})
+}
+
+func TestDiskIOUdev(t *testing.T) {
+	var mps system.MockPS
+	mps.On("DiskIO").Return(counters(), nil)
+
+	d := &DiskIO{
+		ps:            &mps,
+		DeviceTags:    []string{"ID_FS_TYPE", "DEVLINKS", "ID_MISSING"},
+		NameTemplates: []string{"$ID_MISSING", "${ID_FS_LABEL}_$ID_FS_TYPE"},
+		udevDataFile:  fixtureDataFile,
+	}
+	require.NoError(t, d.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	require.Equal(t, uint64(2), acc.NMetrics())
+	require.True(t, acc.HasPoint("diskio", map[string]string{
+		"name":       "data_ext4",
+		"ID_FS_TYPE": "ext4",
+		"DEVLINKS":   "/dev/disk/by-id/ata-SAMSUNG_SSD /dev/disk/by-uuid/1234-abcd",
+	}, "reads", uint64(888)))
+	require.True(t, acc.HasPoint("diskio", map[string]string{"name": "loop0"}, "reads", uint64(1)))
+
+	// The udev data is cached until the file changes.
+	require.Contains(t, d.infoCache, "sda")
+}
+
+func TestDiskIOConfig(t *testing.T) {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadConfig("testdata/diskio.toml"))
+	require.Len(t, c.Inputs, 1)
+
+	var mps system.MockPS
+	mps.On("DiskIO").Return(counters(), nil)
+	d := c.Inputs[0].Input.(*DiskIO)
+	d.ps = &mps
+	d.udevDataFile = fixtureDataFile
+
+	// The agent initializes the input through the RunningInput.
+	require.NoError(t, c.Inputs[0].Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, c.Inputs[0].Gather(&acc))
+	require.Equal(t, uint64(1), acc.NMetrics())
+	require.True(t, acc.HasPoint("diskio",
+		map[string]string{"name": "sda", "ID_FS_TYPE": "ext4"}, "reads", uint64(888)))
+}
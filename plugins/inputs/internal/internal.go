@@ -0,0 +1,35 @@
+package internal
+
+import (
+	"github.com/geekflow/straw/internal/selfstat"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/inputs"
+)
+
+var sampleConfig = `
+  ## No configuration.
+`
+
+// Self reports the statistics the agent collects about itself.
+type Self struct{}
+
+func (*Self) Description() string {
+	return "Collect statistics about the agent itself"
+}
+
+func (*Self) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Self) Gather(acc plugins.Accumulator) error {
+	for _, m := range selfstat.Metrics() {
+		acc.AddCounter(m.Name(), m.Fields(), m.Tags())
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("internal", func() plugins.Input {
+		return &Self{}
+	})
+}
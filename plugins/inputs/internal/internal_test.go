@@ -0,0 +1,20 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/geekflow/straw/internal/selfstat"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	selfstat.Register("gather", "metrics_dropped",
+		map[string]string{"input": "test_internal"}).Incr(3)
+
+	var acc testutil.Accumulator
+	require.NoError(t, (&Self{}).Gather(&acc))
+	acc.AssertContainsTaggedFields(t, "internal_gather",
+		map[string]interface{}{"metrics_dropped": int64(3)},
+		map[string]string{"input": "test_internal"})
+}
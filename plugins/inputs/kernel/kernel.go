@@ -0,0 +1,100 @@
+package kernel
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/inputs"
+	"github.com/geekflow/straw/plugins/inputs/system"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var sampleConfig = `
+  ## Root of the proc filesystem.  By default HOST_PROC or /proc.
+  # proc_root = "/proc"
+`
+
+// Kernel reports kernel statistics from /proc/stat and the available
+// entropy.
+type Kernel struct {
+	ProcRoot string `toml:"proc_root"`
+}
+
+// statFields maps the lines of /proc/stat to fields.  For interrupts only
+// the total, the first value, is used.
+var statFields = map[string]string{
+	"intr":      "interrupts",
+	"ctxt":      "context_switches",
+	"btime":     "boot_time",
+	"processes": "processes_forked",
+}
+
+func (*Kernel) Description() string {
+	return "Read kernel statistics from /proc/stat"
+}
+
+func (*Kernel) SampleConfig() string {
+	return sampleConfig
+}
+
+func (k *Kernel) Gather(acc plugins.Accumulator) error {
+	root := system.HostRoot(k.ProcRoot, "HOST_PROC", "/proc")
+
+	data, err := ioutil.ReadFile(filepath.Join(root, "stat"))
+	if err != nil {
+		return fmt.Errorf("error reading kernel stats: %s", err)
+	}
+
+	// The boot time and the available entropy are gauges, the other fields
+	// counters.
+	counters := make(map[string]interface{})
+	gauges := make(map[string]interface{})
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		values := strings.Fields(scanner.Text())
+		if len(values) < 2 {
+			continue
+		}
+		name, ok := statFields[values[0]]
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseInt(values[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("error parsing %s: %s", values[0], err)
+		}
+		if name == "boot_time" {
+			gauges[name] = v
+		} else {
+			counters[name] = v
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading kernel stats: %s", err)
+	}
+
+	entropy, err := ioutil.ReadFile(filepath.Join(root, "sys", "kernel", "random", "entropy_avail"))
+	if err != nil {
+		return fmt.Errorf("error reading entropy: %s", err)
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(string(entropy)), 10, 64)
+	if err != nil {
+		return fmt.Errorf("error parsing entropy: %s", err)
+	}
+	gauges["entropy_avail"] = v
+
+	acc.AddCounter("kernel", counters, nil)
+	acc.AddGauge("kernel", gauges, nil)
+	return nil
+}
+
+func init() {
+	inputs.Add("kernel", func() plugins.Input {
+		return &Kernel{}
+	})
+}
@@ -0,0 +1,18 @@
+package kernel
+
+import (
+	"testing"
+
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKernel(t *testing.T) {
+	h := testutil.NewInputHarness(&Kernel{ProcRoot: "testdata/proc"})
+	h.RequireGolden(t, "testdata/kernel.golden")
+}
+
+func TestKernelMissingProc(t *testing.T) {
+	var acc testutil.Accumulator
+	require.Error(t, (&Kernel{ProcRoot: "testdata/missing"}).Gather(&acc))
+}
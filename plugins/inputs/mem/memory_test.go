@@ -4,6 +4,8 @@ import (
 	"github.com/geekflow/straw/internal"
 	"github.com/geekflow/straw/plugins/inputs/system"
 	"github.com/geekflow/straw/testutil"
+	"os"
+	"runtime"
 	"testing"
 	"time"
 
@@ -114,3 +116,23 @@ func TestMemStats(t *testing.T) {
 
 	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
 }
+
+func TestMemStatsFixture(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("fixture is a Linux /proc")
+	}
+	// gopsutil only reads the proc filesystem from HOST_PROC, this test must
+	// not run in parallel with others.
+	old, ok := os.LookupEnv("HOST_PROC")
+	require.NoError(t, os.Setenv("HOST_PROC", "testdata/proc"))
+	defer func() {
+		if ok {
+			os.Setenv("HOST_PROC", old)
+		} else {
+			os.Unsetenv("HOST_PROC")
+		}
+	}()
+
+	h := testutil.NewInputHarness(&MemStats{ps: system.NewSystemPS(), platform: "linux"})
+	h.RequireGolden(t, "testdata/mem.golden")
+}
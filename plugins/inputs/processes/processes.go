@@ -0,0 +1,121 @@
+package processes
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/inputs"
+	"github.com/geekflow/straw/plugins/inputs/system"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+var sampleConfig = `
+  ## Root of the proc filesystem.  By default HOST_PROC or /proc.
+  # proc_root = "/proc"
+`
+
+// Processes counts the processes by state and their threads.
+type Processes struct {
+	ProcRoot string `toml:"proc_root"`
+}
+
+// stateFields maps the process states of /proc/<pid>/stat to fields.
+var stateFields = map[byte]string{
+	'R': "running",
+	'S': "sleeping",
+	'D': "blocked",
+	'Z': "zombies",
+	'T': "stopped",
+	't': "stopped",
+	'X': "dead",
+	'x': "dead",
+	'I': "idle",
+	'W': "paging",
+}
+
+func (*Processes) Description() string {
+	return "Count processes by state"
+}
+
+func (*Processes) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Processes) Gather(acc plugins.Accumulator) error {
+	root := system.HostRoot(p.ProcRoot, "HOST_PROC", "/proc")
+
+	dirs, err := ioutil.ReadDir(root)
+	if err != nil {
+		return fmt.Errorf("error reading processes: %s", err)
+	}
+
+	fields := map[string]interface{}{
+		"total":         int64(0),
+		"total_threads": int64(0),
+		"unknown":       int64(0),
+	}
+	for _, name := range stateFields {
+		fields[name] = int64(0)
+	}
+
+	for _, dir := range dirs {
+		if _, err := strconv.Atoi(dir.Name()); err != nil || !dir.IsDir() {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(root, dir.Name(), "stat"))
+		if err != nil {
+			// The process has exited since the directory was read.
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("error reading process %s: %s", dir.Name(), err)
+		}
+
+		state, threads, err := parseStat(data)
+		if err != nil {
+			return fmt.Errorf("error parsing process %s: %s", dir.Name(), err)
+		}
+
+		name, ok := stateFields[state]
+		if !ok {
+			name = "unknown"
+		}
+		fields[name] = fields[name].(int64) + 1
+		fields["total"] = fields["total"].(int64) + 1
+		fields["total_threads"] = fields["total_threads"].(int64) + threads
+	}
+
+	acc.AddGauge("processes", fields, nil)
+	return nil
+}
+
+// parseStat returns the state and number of threads from the contents of
+// /proc/<pid>/stat.  The command name may contain spaces and parentheses, so
+// the fields are read after its last closing parenthesis.
+func parseStat(data []byte) (byte, int64, error) {
+	i := bytes.LastIndexByte(data, ')')
+	if i < 0 {
+		return 0, 0, fmt.Errorf("missing command name")
+	}
+
+	fields := bytes.Fields(data[i+1:])
+	// state is the first field after the name, num_threads the eighteenth.
+	if len(fields) < 18 || len(fields[0]) != 1 {
+		return 0, 0, fmt.Errorf("too few fields")
+	}
+	threads, err := strconv.ParseInt(string(fields[17]), 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return fields[0][0], threads, nil
+}
+
+func init() {
+	inputs.Add("processes", func() plugins.Input {
+		return &Processes{}
+	})
+}
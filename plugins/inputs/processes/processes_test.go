@@ -0,0 +1,23 @@
+package processes
+
+import (
+	"testing"
+
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcesses(t *testing.T) {
+	h := testutil.NewInputHarness(&Processes{ProcRoot: "testdata/proc"})
+	h.RequireGolden(t, "testdata/processes.golden")
+}
+
+func TestParseStat(t *testing.T) {
+	state, threads, err := parseStat([]byte("107 ((sd-pam) x) S 1 107 107 0 -1 4194560 51 0 0 0 0 0 0 0 20 0 3 0"))
+	require.NoError(t, err)
+	require.Equal(t, byte('S'), state)
+	require.Equal(t, int64(3), threads)
+
+	_, _, err = parseStat([]byte("1 (init) S 0"))
+	require.Error(t, err)
+}
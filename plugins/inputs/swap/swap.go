@@ -0,0 +1,61 @@
+package swap
+
+import (
+	"fmt"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/inputs"
+	"github.com/geekflow/straw/plugins/inputs/system"
+)
+
+var sampleConfig = `
+  ## Root of the proc filesystem, read on Linux.  By default HOST_PROC or
+  ## /proc.
+  # proc_root = "/proc"
+`
+
+// SwapStats reports the swap space usage and the pages swapped in and out.
+type SwapStats struct {
+	// ps is only used on platforms other than Linux, in swap_other.go; on
+	// Linux the proc filesystem is read directly.
+	ps system.PS
+
+	ProcRoot string `toml:"proc_root"`
+}
+
+func (*SwapStats) Description() string {
+	return "Read metrics about swap memory usage"
+}
+
+func (*SwapStats) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SwapStats) Gather(acc plugins.Accumulator) error {
+	swap, err := s.swapStat()
+	if err != nil {
+		return fmt.Errorf("error getting swap memory info: %s", err)
+	}
+
+	fields := map[string]interface{}{
+		"total":        swap.Total,
+		"used":         swap.Used,
+		"free":         swap.Free,
+		"used_percent": swap.UsedPercent,
+	}
+	acc.AddGauge("swap", fields, nil)
+
+	fields = map[string]interface{}{
+		"in":  swap.Sin,
+		"out": swap.Sout,
+	}
+	acc.AddCounter("swap", fields, nil)
+
+	return nil
+}
+
+func init() {
+	ps := system.NewSystemPS()
+	inputs.Add("swap", func() plugins.Input {
+		return &SwapStats{ps: ps}
+	})
+}
@@ -0,0 +1,70 @@
+package swap
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/geekflow/straw/plugins/inputs/system"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/mem"
+)
+
+// swapStat reads the swap usage from meminfo and the pages swapped from
+// vmstat.
+func (s *SwapStats) swapStat() (*mem.SwapMemoryStat, error) {
+	root := system.HostRoot(s.ProcRoot, "HOST_PROC", "/proc")
+	meminfo, err := readValues(filepath.Join(root, "meminfo"))
+	if err != nil {
+		return nil, err
+	}
+	total, ok := meminfo["SwapTotal"]
+	if !ok {
+		return nil, fmt.Errorf("missing SwapTotal in meminfo")
+	}
+	free := meminfo["SwapFree"]
+
+	swap := &mem.SwapMemoryStat{
+		Total: total * 1024,
+		Free:  free * 1024,
+		Used:  (total - free) * 1024,
+	}
+	if total > 0 {
+		swap.UsedPercent = 100 * float64(total-free) / float64(total)
+	}
+
+	vmstat, err := readValues(filepath.Join(root, "vmstat"))
+	if err != nil {
+		return nil, err
+	}
+	pageSize := uint64(os.Getpagesize())
+	swap.Sin = vmstat["pswpin"] * pageSize
+	swap.Sout = vmstat["pswpout"] * pageSize
+	return swap, nil
+}
+
+// readValues reads the "key value" or "key: value kB" lines of a proc file.
+func readValues(path string) (map[string]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[strings.TrimSuffix(fields[0], ":")] = v
+	}
+	return values, scanner.Err()
+}
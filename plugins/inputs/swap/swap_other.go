@@ -0,0 +1,11 @@
+// +build !linux
+
+package swap
+
+import (
+	"github.com/shirou/gopsutil/mem"
+)
+
+func (s *SwapStats) swapStat() (*mem.SwapMemoryStat, error) {
+	return s.ps.SwapStat()
+}
@@ -0,0 +1,17 @@
+package swap
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/geekflow/straw/testutil"
+)
+
+func TestSwapStats(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("fixture is a Linux /proc")
+	}
+
+	h := testutil.NewInputHarness(&SwapStats{ProcRoot: "testdata/proc"})
+	h.RequireGolden(t, "testdata/swap.golden")
+}
@@ -101,7 +101,7 @@ func (m *MockPS) SwapStat() (*mem.SwapMemoryStat, error) {
 	return r0, r1
 }
 
-func (m *MockPS) Temperature() ([]host.TemperatureStat, error) {
+func (m *MockPS) Temperature(sysRoot string) ([]host.TemperatureStat, error) {
 	ret := m.Called()
 
 	r0 := ret.Get(0).([]host.TemperatureStat)
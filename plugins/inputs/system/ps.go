@@ -23,7 +23,7 @@ type PS interface {
 	VMStat() (*mem.VirtualMemoryStat, error)
 	SwapStat() (*mem.SwapMemoryStat, error)
 	NetConnections() ([]net.ConnectionStat, error)
-	Temperature() ([]host.TemperatureStat, error)
+	Temperature(sysRoot string) ([]host.TemperatureStat, error)
 }
 
 type PSDiskDeps interface {
@@ -40,6 +40,19 @@ func add(acc plugins.Accumulator,
 	}
 }
 
+// HostRoot returns the root of a host filesystem, such as /proc or /sys, for
+// inputs that read it directly: root if set, otherwise the value of the
+// environment variable env, such as HOST_PROC, or def.
+func HostRoot(root, env, def string) string {
+	if root != "" {
+		return root
+	}
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+	return def
+}
+
 func NewSystemPS() *SystemPS {
 	return &SystemPS{&SystemPSDisk{}}
 }
@@ -169,17 +182,6 @@ func (s *SystemPS) SwapStat() (*mem.SwapMemoryStat, error) {
 	return mem.SwapMemory()
 }
 
-func (s *SystemPS) Temperature() ([]host.TemperatureStat, error) {
-	temp, err := host.SensorsTemperatures()
-	if err != nil {
-		_, ok := err.(*host.Warnings)
-		if !ok {
-			return temp, err
-		}
-	}
-	return temp, nil
-}
-
 func (s *SystemPSDisk) Partitions(all bool) ([]disk.PartitionStat, error) {
 	return disk.Partitions(all)
 }
@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
-	"github.com/geekflow/straw/internal/logger"
 	"github.com/geekflow/straw/plugins"
 	"github.com/geekflow/straw/plugins/inputs"
 	"os"
@@ -14,11 +13,10 @@ import (
 	"github.com/shirou/gopsutil/cpu"
 	"github.com/shirou/gopsutil/host"
 	"github.com/shirou/gopsutil/load"
+	log "github.com/sirupsen/logrus"
 )
 
-type SystemStats struct {
-	Log logger.Logger
-}
+type SystemStats struct{}
 
 func (*SystemStats) Description() string {
 	return "Read metrics about system load & uptime"
@@ -43,19 +41,22 @@ func (s *SystemStats) Gather(acc plugins.Accumulator) error {
 	}
 
 	fields := map[string]interface{}{
-		"load1":  loadavg.Load1,
-		"load5":  loadavg.Load5,
-		"load15": loadavg.Load15,
 		"n_cpus": numCPUs,
 	}
+	// Load averages are not available on all platforms.
+	if loadavg != nil {
+		fields["load1"] = loadavg.Load1
+		fields["load5"] = loadavg.Load5
+		fields["load15"] = loadavg.Load15
+	}
 
 	users, err := host.Users()
 	if err == nil {
 		fields["n_users"] = len(users)
 	} else if os.IsNotExist(err) {
-		s.Log.Logger.Debugf("Reading users: %s", err.Error())
+		log.Debugf("[inputs.system] Reading users: %s", err)
 	} else if os.IsPermission(err) {
-		s.Log.Logger.Debug(err.Error())
+		log.Debugf("[inputs.system] %s", err)
 	}
 
 	now := time.Now()
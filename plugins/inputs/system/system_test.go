@@ -0,0 +1,23 @@
+package system
+
+import (
+	"testing"
+
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemStats(t *testing.T) {
+	var acc testutil.Accumulator
+	require.NoError(t, (&SystemStats{}).Gather(&acc))
+
+	require.True(t, acc.HasField("system", "n_cpus"))
+	require.True(t, acc.HasField("system", "uptime"))
+	require.True(t, acc.HasStringField("system", "uptime_format"))
+}
+
+func TestFormatUptime(t *testing.T) {
+	require.Equal(t, " 0:05", formatUptime(300))
+	require.Equal(t, "1 day,  1:01", formatUptime(90060))
+	require.Equal(t, "14 days, 11:07", formatUptime(1249632))
+}
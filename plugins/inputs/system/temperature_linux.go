@@ -0,0 +1,143 @@
+package system
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/host"
+)
+
+// Temperature reads the temperature channels of the hwmon devices, falling
+// back to the thermal zones on systems without hwmon.  The sys filesystem is
+// read from sysRoot if set, otherwise from HOST_SYS or /sys.
+//
+// Each key is the sensor name followed by "_input" for the reading, "_max"
+// or "_crit" for a threshold, in degrees Celsius, or "_alarm", "_max_alarm"
+// or "_crit_alarm" for an alarm flag, 0 or 1.  The sensor name of a hwmon
+// channel is the device, chip name and channel label, such as
+// "hwmon0_coretemp_core0", so that the same chip and label on several
+// sockets are told apart.  The sensor name of a thermal zone is its type.
+func (s *SystemPS) Temperature(sysRoot string) ([]host.TemperatureStat, error) {
+	return hwmonTemperatures(HostRoot(sysRoot, "HOST_SYS", "/sys"))
+}
+
+func hwmonTemperatures(root string) ([]host.TemperatureStat, error) {
+	inputs, err := filepath.Glob(filepath.Join(root, "class", "hwmon", "hwmon*", "temp*_input"))
+	if err != nil {
+		return nil, err
+	}
+	// Some kernels have the channels in the device directory.
+	device, err := filepath.Glob(filepath.Join(root, "class", "hwmon", "hwmon*", "device", "temp*_input"))
+	if err != nil {
+		return nil, err
+	}
+	inputs = append(inputs, device...)
+	if len(inputs) == 0 {
+		return thermalZones(root)
+	}
+	sort.Strings(inputs)
+
+	var temps []host.TemperatureStat
+	for _, input := range inputs {
+		dir := filepath.Dir(input)
+		channel := strings.TrimSuffix(filepath.Base(input), "_input")
+
+		value, err := readMilli(input)
+		if err != nil {
+			// Channels of absent sensors fail to read.
+			continue
+		}
+
+		name := sensorName(dir, channel)
+		temps = append(temps, host.TemperatureStat{SensorKey: name + "_input", Temperature: value})
+		for _, suffix := range []string{"max", "crit"} {
+			if v, err := readMilli(filepath.Join(dir, channel+"_"+suffix)); err == nil {
+				temps = append(temps, host.TemperatureStat{SensorKey: name + "_" + suffix, Temperature: v})
+			}
+		}
+		for _, suffix := range []string{"alarm", "max_alarm", "crit_alarm"} {
+			if v, err := readHostString(filepath.Join(dir, channel+"_"+suffix)); err == nil {
+				flag := 0.0
+				if v != "0" {
+					flag = 1
+				}
+				temps = append(temps, host.TemperatureStat{SensorKey: name + "_" + suffix, Temperature: flag})
+			}
+		}
+	}
+	return temps, nil
+}
+
+// sensorName returns the hwmon device, the chip name and the label of the
+// channel, or the channel name if it has no label.
+func sensorName(dir, channel string) string {
+	hwmon := dir
+	if filepath.Base(hwmon) == "device" {
+		hwmon = filepath.Dir(hwmon)
+	}
+	parts := []string{filepath.Base(hwmon)}
+
+	chip, err := readHostString(filepath.Join(dir, "name"))
+	if err != nil {
+		chip, _ = readHostString(filepath.Join(hwmon, "name"))
+	}
+	if chip != "" {
+		parts = append(parts, chip)
+	}
+
+	label, err := readHostString(filepath.Join(dir, channel+"_label"))
+	if err != nil || label == "" {
+		label = channel
+	}
+	parts = append(parts, strings.Join(strings.Fields(strings.ToLower(label)), ""))
+
+	return strings.Join(parts, "_")
+}
+
+// thermalZones reads the temperatures of the thermal zones, named after
+// their type.
+func thermalZones(root string) ([]host.TemperatureStat, error) {
+	zones, err := filepath.Glob(filepath.Join(root, "class", "thermal", "thermal_zone*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(zones)
+
+	var temps []host.TemperatureStat
+	for _, zone := range zones {
+		name, err := readHostString(filepath.Join(zone, "type"))
+		if err != nil {
+			continue
+		}
+		value, err := readMilli(filepath.Join(zone, "temp"))
+		if err != nil {
+			continue
+		}
+		temps = append(temps, host.TemperatureStat{SensorKey: name + "_input", Temperature: value})
+	}
+	return temps, nil
+}
+
+func readHostString(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// readMilli reads a value in millidegrees and returns it in degrees.
+func readMilli(path string) (float64, error) {
+	s, err := readHostString(path)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return float64(v) / 1000, nil
+}
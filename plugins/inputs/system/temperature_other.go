@@ -0,0 +1,20 @@
+// +build !linux
+
+package system
+
+import (
+	"github.com/shirou/gopsutil/host"
+)
+
+// Temperature reads the temperature sensors with gopsutil, sysRoot is not
+// used.
+func (s *SystemPS) Temperature(sysRoot string) ([]host.TemperatureStat, error) {
+	temp, err := host.SensorsTemperatures()
+	if err != nil {
+		_, ok := err.(*host.Warnings)
+		if !ok {
+			return temp, err
+		}
+	}
+	return temp, nil
+}
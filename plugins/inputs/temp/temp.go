@@ -0,0 +1,93 @@
+package temp
+
+import (
+	"fmt"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/inputs"
+	"github.com/geekflow/straw/plugins/inputs/system"
+	"strings"
+)
+
+var sampleConfig = `
+  ## Root of the sys filesystem, read on Linux only.  By default HOST_SYS or
+  ## /sys.
+  # sys_root = "/sys"
+`
+
+// suffixes are the suffixes of the sensor keys reported on Linux and the
+// fields they are stored in.  Longer suffixes come first.
+var suffixes = []struct {
+	suffix string
+	field  string
+	alarm  bool
+}{
+	{"_max_alarm", "max_alarm", true},
+	{"_crit_alarm", "crit_alarm", true},
+	{"_alarm", "alarm", true},
+	{"_input", "temp", false},
+	{"_max", "max", false},
+	{"_crit", "crit", false},
+}
+
+// Temperature reports the temperature of each sensor.
+type Temperature struct {
+	SysRoot string `toml:"sys_root"`
+
+	ps system.PS
+}
+
+func (*Temperature) Description() string {
+	return "Read metrics about temperature"
+}
+
+func (*Temperature) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *Temperature) Gather(acc plugins.Accumulator) error {
+	temps, err := t.ps.Temperature(t.SysRoot)
+	if err != nil {
+		return fmt.Errorf("error getting temperatures: %s", err)
+	}
+
+	// Readings, thresholds and alarms of a sensor are reported as separate
+	// keys; gather them into one metric per sensor.
+	var names []string
+	sensors := make(map[string]map[string]interface{})
+	for _, temp := range temps {
+		name, field, value := parseKey(temp.SensorKey, temp.Temperature)
+		fields, ok := sensors[name]
+		if !ok {
+			fields = make(map[string]interface{})
+			sensors[name] = fields
+			names = append(names, name)
+		}
+		fields[field] = value
+	}
+
+	for _, name := range names {
+		acc.AddGauge("temp", sensors[name], map[string]string{"sensor": name})
+	}
+	return nil
+}
+
+// parseKey returns the sensor, field and value of a temperature reported by
+// the system.  Keys without a known suffix are the temperature of the sensor.
+func parseKey(key string, value float64) (string, string, interface{}) {
+	for _, s := range suffixes {
+		if strings.HasSuffix(key, s.suffix) && len(key) > len(s.suffix) {
+			if s.alarm {
+				return strings.TrimSuffix(key, s.suffix), s.field, value != 0
+			}
+			return strings.TrimSuffix(key, s.suffix), s.field, value
+		}
+	}
+	return key, "temp", value
+}
+
+func init() {
+	ps := system.NewSystemPS()
+	inputs.Add("temp", func() plugins.Input {
+		return &Temperature{ps: ps}
+	})
+}
@@ -0,0 +1,50 @@
+package temp
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/geekflow/straw/plugins/inputs/system"
+	"github.com/geekflow/straw/testutil"
+	"github.com/shirou/gopsutil/host"
+)
+
+func TestHwmon(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("fixture is a Linux /sys")
+	}
+
+	h := testutil.NewInputHarness(&Temperature{SysRoot: "testdata/sys", ps: system.NewSystemPS()})
+	h.RequireGolden(t, "testdata/hwmon.golden")
+}
+
+func TestThermalZones(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("fixture is a Linux /sys")
+	}
+
+	h := testutil.NewInputHarness(&Temperature{SysRoot: "testdata/thermal", ps: system.NewSystemPS()})
+	h.RequireGolden(t, "testdata/thermal.golden")
+}
+
+func TestSensorKeys(t *testing.T) {
+	var mps system.MockPS
+	mps.On("Temperature").Return([]host.TemperatureStat{
+		{SensorKey: "hwmon0_coretemp_core0_input", Temperature: 47},
+		{SensorKey: "hwmon0_coretemp_core0_crit", Temperature: 100},
+		{SensorKey: "hwmon0_coretemp_core0_crit_alarm", Temperature: 1},
+		{SensorKey: "TC0P", Temperature: 52},
+	}, nil)
+
+	var acc testutil.Accumulator
+	if err := (&Temperature{ps: &mps}).Gather(&acc); err != nil {
+		t.Fatal(err)
+	}
+
+	acc.AssertContainsTaggedFields(t, "temp",
+		map[string]interface{}{"temp": 47.0, "crit": 100.0, "crit_alarm": true},
+		map[string]string{"sensor": "hwmon0_coretemp_core0"})
+	acc.AssertContainsTaggedFields(t, "temp",
+		map[string]interface{}{"temp": 52.0},
+		map[string]string{"sensor": "TC0P"})
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/internal/selfstat"
 	"github.com/geekflow/straw/plugins/serializers/influx"
 	log "github.com/sirupsen/logrus"
 	"io"
@@ -100,6 +101,9 @@ type HTTPConfig struct {
 
 	InfluxUintSupport bool `toml:"influx_uint_support"`
 	Serializer        *influx.Serializer
+	// FieldTypes, if set, learns the field types reported in field type
+	// conflicts so that later metrics are made consistent before writing.
+	FieldTypes *influx.FieldTypes
 	//Log               internal.Logger
 }
 
@@ -107,6 +111,7 @@ type httpClient struct {
 	client           *http.Client
 	config           HTTPConfig
 	createdDatabases map[string]bool
+	pointsDropped    *selfstat.Stat
 
 	//log internal.Logger
 }
@@ -176,6 +181,8 @@ func NewHTTPClient(config HTTPConfig) (*httpClient, error) {
 		},
 		createdDatabases: make(map[string]bool),
 		config:           config,
+		pointsDropped: selfstat.Register("write", "points_dropped",
+			map[string]string{"output": "influxdb", "url": config.URL.String()}),
 		//log:              config.Log,
 	}
 	return client, nil
@@ -349,13 +356,24 @@ func (c *httpClient) writeBatch(ctx context.Context, db string, metrics []intern
 	}
 
 	// Other partial write errors, such as "field type conflict", are not
-	// correctable at this point and so the point is dropped instead of
-	// retrying.
+	// correctable at this point.  The server has written the other points of
+	// the batch, so the rejected points are dropped and the batch is not
+	// retried.
 	if strings.Contains(desc, errStringPartialWrite) {
-		// c.log.Errorf("When writing to [%s]: received error %v; discarding points",
-		//	c.URL(), desc)
-		log.Errorf("When writing to [%s]: received error %v; discarding points",
-			c.URL(), desc)
+		conflicts, dropped := influx.ParsePartialWrite(desc)
+		if c.config.FieldTypes != nil {
+			for _, conflict := range conflicts {
+				c.config.FieldTypes.Set(conflict.Measurement, conflict.Field, conflict.Existing)
+			}
+		}
+		if dropped < 0 {
+			log.Errorf("When writing to [%s]: received error %v; discarding points",
+				c.URL(), desc)
+			return nil
+		}
+		c.pointsDropped.Incr(int64(dropped))
+		log.Errorf("When writing to [%s]: received error %v; discarding %d of %d points",
+			c.URL(), desc, dropped, len(metrics))
 		return nil
 	}
 
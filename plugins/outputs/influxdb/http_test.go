@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/internal/selfstat"
 	"github.com/geekflow/straw/metric"
 	"github.com/geekflow/straw/plugins/outputs/influxdb"
 	"github.com/geekflow/straw/testutil"
@@ -732,3 +733,43 @@ func TestHTTP_WriteDatabaseTagWorksOnRetry(t *testing.T) {
 	err = client.Write(ctx, metrics)
 	require.NoError(t, err)
 }
+
+func TestHTTP_PartialWriteCountsDropped(t *testing.T) {
+	writes := 0
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writes++
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "partial write: field type conflict: input field \"value\" ` +
+				`on measurement \"cpu\" is type integer, already exists as type float dropped=1"}`))
+		}),
+	)
+	defer ts.Close()
+
+	addr := &url.URL{
+		Scheme: "http",
+		Host:   ts.Listener.Addr().String(),
+	}
+
+	client, err := influxdb.NewHTTPClient(influxdb.HTTPConfig{
+		URL:      addr,
+		Database: "straw",
+	})
+	require.NoError(t, err)
+
+	metrics := []internal.Metric{
+		testutil.MustMetric("cpu", map[string]string{},
+			map[string]interface{}{"value": int64(42)}, time.Unix(0, 0)),
+		testutil.MustMetric("mem", map[string]string{},
+			map[string]interface{}{"value": 42.0}, time.Unix(0, 0)),
+	}
+
+	// The batch is not retried, the rejected point is counted.
+	err = client.Write(context.Background(), metrics)
+	require.NoError(t, err)
+	require.Equal(t, 1, writes)
+
+	dropped := selfstat.Register("write", "points_dropped",
+		map[string]string{"output": "influxdb", "url": addr.String()})
+	require.Equal(t, int64(1), dropped.Get())
+}
@@ -49,11 +49,13 @@ type InfluxDB struct {
 	ContentEncoding      string            `toml:"content_encoding"`
 	SkipDatabaseCreation bool              `toml:"skip_database_creation"`
 	InfluxUintSupport    bool              `toml:"influx_uint_support"`
+	FieldTypeConsistency string            `toml:"field_type_consistency"`
 	tls.ClientConfig
 
 	Precision string // precision deprecated in 1.0; value is ignored
 
-	clients []Client
+	clients    []Client
+	fieldTypes *influx.FieldTypes
 
 	CreateHTTPClientF func(config *HTTPConfig) (Client, error)
 	CreateUDPClientF  func(config *UDPConfig) (Client, error)
@@ -130,11 +132,37 @@ var sampleConfig = `
   ## integer values.  Enabling this option will result in field type errors if
   ## existing data has been written.
   # influx_uint_support = false
+
+  ## Keep the type of each field consistent with the first type written, to
+  ## avoid field type conflicts that cause InfluxDB to reject points.  Set to
+  ## "coerce" to convert values where possible, such as integer to float or
+  ## boolean to integer, and drop the field otherwise; set to "drop" to
+  ## always drop conflicting fields.  Types reported in field type conflict
+  ## errors from the server are also remembered.
+  # field_type_consistency = ""
+
+  ## Points the server rejects in a partial write, such as points with a
+  ## field type conflict, are dropped; the server writes the other points of
+  ## the batch, so the batch is not retried.  The number of dropped points is
+  ## logged and counted in the points_dropped field of internal_write.
 `
 
 func (i *InfluxDB) Connect() error {
 	ctx := context.Background()
 
+	if i.FieldTypeConsistency != "" {
+		mode, err := influx.ParseFieldConflictMode(i.FieldTypeConsistency)
+		if err != nil {
+			return err
+		}
+
+		var typeSupport influx.FieldTypeSupport
+		if i.InfluxUintSupport {
+			typeSupport = influx.UintSupport
+		}
+		i.fieldTypes = influx.NewFieldTypes(mode, typeSupport)
+	}
+
 	urls := make([]string, 0, len(i.URLs))
 	urls = append(urls, i.URLs...)
 	if i.URL != "" {
@@ -202,6 +230,13 @@ func (i *InfluxDB) SampleConfig() string {
 func (i *InfluxDB) Write(metrics []internal.Metric) error {
 	ctx := context.Background()
 
+	if i.fieldTypes != nil {
+		metrics = i.fieldTypes.Filter(metrics)
+		if len(metrics) == 0 {
+			return nil
+		}
+	}
+
 	var err error
 	p := rand.Perm(len(i.clients))
 	for _, n := range p {
@@ -270,6 +305,7 @@ func (i *InfluxDB) httpClient(ctx context.Context, url *url.URL, proxy *url.URL)
 		RetentionPolicy:      i.RetentionPolicy,
 		Consistency:          i.WriteConsistency,
 		Serializer:           i.newSerializer(),
+		FieldTypes:           i.fieldTypes,
 		//Log:                  i.Log,
 	}
 
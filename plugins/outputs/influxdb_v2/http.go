@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/internal/selfstat"
 	"github.com/geekflow/straw/plugins/serializers/influx"
 	"io"
 	"io/ioutil"
@@ -53,6 +54,9 @@ type HTTPConfig struct {
 	TLSConfig        *tls.Config
 
 	Serializer *influx.Serializer
+	// FieldTypes, if set, learns the field types reported in field type
+	// conflicts so that later metrics are made consistent before writing.
+	FieldTypes *influx.FieldTypes
 }
 
 type httpClient struct {
@@ -66,8 +70,11 @@ type httpClient struct {
 
 	client     *http.Client
 	serializer *influx.Serializer
+	fieldTypes *influx.FieldTypes
 	url        *url.URL
 	retryTime  time.Time
+
+	pointsDropped *selfstat.Stat
 }
 
 func NewHTTPClient(config *HTTPConfig) (*httpClient, error) {
@@ -127,6 +134,7 @@ func NewHTTPClient(config *HTTPConfig) (*httpClient, error) {
 
 	client := &httpClient{
 		serializer: serializer,
+		fieldTypes: config.FieldTypes,
 		client: &http.Client{
 			Timeout:   timeout,
 			Transport: transport,
@@ -139,6 +147,8 @@ func NewHTTPClient(config *HTTPConfig) (*httpClient, error) {
 		Bucket:           config.Bucket,
 		BucketTag:        config.BucketTag,
 		ExcludeBucketTag: config.ExcludeBucketTag,
+		pointsDropped: selfstat.Register("write", "points_dropped",
+			map[string]string{"output": "influxdb_v2", "url": config.URL.String()}),
 	}
 	return client, nil
 }
@@ -242,8 +252,21 @@ func (c *httpClient) writeBatch(ctx context.Context, bucket string, metrics []in
 	}
 
 	switch resp.StatusCode {
-	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
-		log.Printf("E! [outputs.influxdb_v2] Failed to write metric: %s\n", desc)
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		// The server rejected some or all of the points, most often due to
+		// a field type conflict; retrying the batch would fail again.  The
+		// points it accepted have been written, the others are dropped.
+		dropped := c.learnFieldTypes(desc)
+		if resp.StatusCode == http.StatusRequestEntityTooLarge {
+			dropped = len(metrics)
+		}
+		if dropped < 0 {
+			log.Printf("E! [outputs.influxdb_v2] Failed to write metric: %s\n", desc)
+			return nil
+		}
+		c.pointsDropped.Incr(int64(dropped))
+		log.Printf("E! [outputs.influxdb_v2] Failed to write metric: %s; discarding %d of %d points\n",
+			desc, dropped, len(metrics))
 		return nil
 	case http.StatusUnauthorized, http.StatusForbidden:
 		return fmt.Errorf("failed to write metric: %s", desc)
@@ -284,6 +307,19 @@ func (c *httpClient) writeBatch(ctx context.Context, bucket string, metrics []in
 	}
 }
 
+// learnFieldTypes records the types reported in field type conflicts and
+// returns the number of points the server dropped, or -1 if it is not
+// reported.
+func (c *httpClient) learnFieldTypes(desc string) int {
+	conflicts, dropped := influx.ParsePartialWrite(desc)
+	if c.fieldTypes != nil {
+		for _, conflict := range conflicts {
+			c.fieldTypes.Set(conflict.Measurement, conflict.Field, conflict.Existing)
+		}
+	}
+	return dropped
+}
+
 func (c *httpClient) makeWriteRequest(url string, body io.Reader) (*http.Request, error) {
 	var err error
 
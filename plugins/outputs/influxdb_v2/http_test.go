@@ -3,6 +3,7 @@ package influxdb_v2_test
 import (
 	"context"
 	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/internal/selfstat"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	influxdb "github.com/geekflow/straw/plugins/outputs/influxdb_v2"
+	"github.com/geekflow/straw/plugins/serializers/influx"
 	"github.com/geekflow/straw/testutil"
 	"github.com/stretchr/testify/require"
 )
@@ -111,3 +113,51 @@ func TestWriteBucketTagWorksOnRetry(t *testing.T) {
 	err = client.Write(ctx, metrics)
 	require.NoError(t, err)
 }
+
+func TestWriteFieldTypeConflictLearnsType(t *testing.T) {
+	var bodies []string
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			bodies = append(bodies, string(body))
+
+			if len(bodies) == 1 {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				w.Write([]byte(`{"code":"unprocessable entity","message":"failure writing points to database: ` +
+					`partial write: field type conflict: input field \"value\" on measurement \"cpu\" ` +
+					`is type integer, already exists as type float dropped=1"}`))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+	defer ts.Close()
+
+	fieldTypes := influx.NewFieldTypes(influx.CoerceConflicts, 0)
+	config := &influxdb.HTTPConfig{
+		URL:             genURL("http://" + ts.Listener.Addr().String()),
+		Bucket:          "straw",
+		ContentEncoding: "identity",
+		FieldTypes:      fieldTypes,
+	}
+
+	client, err := influxdb.NewHTTPClient(config)
+	require.NoError(t, err)
+
+	m := testutil.MustMetric("cpu", map[string]string{},
+		map[string]interface{}{"value": int64(42)}, time.Unix(0, 0))
+
+	// The rejected points are not retried.
+	ctx := context.Background()
+	err = client.Write(ctx, fieldTypes.Filter([]internal.Metric{m}))
+	require.NoError(t, err)
+
+	err = client.Write(ctx, fieldTypes.Filter([]internal.Metric{m}))
+	require.NoError(t, err)
+	require.Equal(t, []string{"cpu value=42i 0\n", "cpu value=42 0\n"}, bodies)
+
+	dropped := selfstat.Register("write", "points_dropped",
+		map[string]string{"output": "influxdb_v2", "url": config.URL.String()})
+	require.Equal(t, int64(1), dropped.Get())
+}
@@ -65,6 +65,19 @@ var sampleConfig = `
   ## Enable or disable uint support for writing uints influxdb 2.0.
   # influx_uint_support = false
 
+  ## Keep the type of each field consistent with the first type written, to
+  ## avoid field type conflicts that cause InfluxDB to reject points.  Set to
+  ## "coerce" to convert values where possible, such as integer to float or
+  ## boolean to integer, and drop the field otherwise; set to "drop" to
+  ## always drop conflicting fields.  Types reported in field type conflict
+  ## errors from the server are also remembered.
+  # field_type_consistency = ""
+
+  ## Points the server rejects in a partial write, such as points with a
+  ## field type conflict, are dropped; the server writes the other points of
+  ## the batch, so the batch is not retried.  The number of dropped points is
+  ## logged and counted in the points_dropped field of internal_write.
+
   ## Optional TLS Config for use on HTTP connections.
   # tls_ca = "/etc/telegraf/ca.pem"
   # tls_cert = "/etc/telegraf/cert.pem"
@@ -93,14 +106,29 @@ type InfluxDB struct {
 	UserAgent        string            `toml:"user_agent"`
 	ContentEncoding  string            `toml:"content_encoding"`
 	UintSupport      bool              `toml:"influx_uint_support"`
+	FieldTypes       string            `toml:"field_type_consistency"`
 	tls.ClientConfig
 
-	clients []Client
+	clients    []Client
+	fieldTypes *influx.FieldTypes
 }
 
 func (i *InfluxDB) Connect() error {
 	ctx := context.Background()
 
+	if i.FieldTypes != "" {
+		mode, err := influx.ParseFieldConflictMode(i.FieldTypes)
+		if err != nil {
+			return err
+		}
+
+		var typeSupport influx.FieldTypeSupport
+		if i.UintSupport {
+			typeSupport = influx.UintSupport
+		}
+		i.fieldTypes = influx.NewFieldTypes(mode, typeSupport)
+	}
+
 	if len(i.URLs) == 0 {
 		i.URLs = append(i.URLs, defaultURL)
 	}
@@ -155,6 +183,13 @@ func (i *InfluxDB) SampleConfig() string {
 func (i *InfluxDB) Write(metrics []internal.Metric) error {
 	ctx := context.Background()
 
+	if i.fieldTypes != nil {
+		metrics = i.fieldTypes.Filter(metrics)
+		if len(metrics) == 0 {
+			return nil
+		}
+	}
+
 	var err error
 	p := rand.Perm(len(i.clients))
 	for _, n := range p {
@@ -190,6 +225,7 @@ func (i *InfluxDB) getHTTPClient(ctx context.Context, url *url.URL, proxy *url.U
 		ContentEncoding:  i.ContentEncoding,
 		TLSConfig:        tlsConfig,
 		Serializer:       i.newSerializer(),
+		FieldTypes:       i.fieldTypes,
 	}
 
 	c, err := NewHTTPClient(config)
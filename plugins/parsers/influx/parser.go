@@ -0,0 +1,334 @@
+package influx
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/metric"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseError indicates an error in a line of line protocol.
+type ParseError struct {
+	LineNumber int
+	Line       string
+	msg        string
+}
+
+func (e *ParseError) Error() string {
+	line := e.Line
+	if len(line) > 64 {
+		line = line[:64] + "..."
+	}
+	return fmt.Sprintf("metric parse error: %s at line %d: %q", e.msg, e.LineNumber, line)
+}
+
+// Parser parses InfluxDB line protocol as written by the influx serializer.
+type Parser struct {
+	// DefaultTags are added to each metric unless the metric has a tag with
+	// the same key.
+	DefaultTags map[string]string
+	// TimeFunc returns the time of metrics without a timestamp.
+	TimeFunc func() time.Time
+}
+
+// NewParser returns a Parser.
+func NewParser() *Parser {
+	return &Parser{TimeFunc: time.Now}
+}
+
+// Parse parses all metrics in buf.
+func (p *Parser) Parse(buf []byte) ([]internal.Metric, error) {
+	sp := p.NewStreamParser(bytes.NewReader(buf))
+
+	var metrics []internal.Metric
+	for {
+		m, err := sp.Next()
+		if err == io.EOF {
+			return metrics, nil
+		}
+		if err != nil {
+			return metrics, err
+		}
+		metrics = append(metrics, m)
+	}
+}
+
+// ParseLine parses a single line of line protocol.
+func (p *Parser) ParseLine(line string) (internal.Metric, error) {
+	m, err := p.parseLine(line)
+	if err != nil {
+		return nil, &ParseError{LineNumber: 1, Line: line, msg: err.Error()}
+	}
+	return m, nil
+}
+
+// StreamParser parses line protocol from a reader one metric at a time.
+type StreamParser struct {
+	parser *Parser
+	reader *bufio.Reader
+	lineNo int
+}
+
+// NewStreamParser returns a StreamParser reading from r.
+func (p *Parser) NewStreamParser(r io.Reader) *StreamParser {
+	return &StreamParser{parser: p, reader: bufio.NewReader(r)}
+}
+
+// Next returns the next metric.  Empty lines and comments are skipped.  When
+// all metrics are read the error is io.EOF.  After a ParseError the parser
+// may be resumed with the following line by calling Next again.
+func (sp *StreamParser) Next() (internal.Metric, error) {
+	for {
+		line, err := sp.readLine()
+		if line == "" && err != nil {
+			return nil, err
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed[0] == '#' {
+			continue
+		}
+
+		m, perr := sp.parser.parseLine(strings.TrimRight(line, "\r\n"))
+		if perr != nil {
+			return nil, &ParseError{LineNumber: sp.lineNo, Line: line, msg: perr.Error()}
+		}
+		return m, nil
+	}
+}
+
+// readLine reads up to the next newline that is not inside a string field.
+// Quotes are only significant after the measurement and tags, which may
+// contain them unescaped.
+func (sp *StreamParser) readLine() (string, error) {
+	var b strings.Builder
+	inFields := false
+	inQuote := false
+	escaped := false
+	start := sp.lineNo + 1
+	for {
+		c, err := sp.reader.ReadByte()
+		if err != nil {
+			sp.lineNo = start
+			return b.String(), err
+		}
+
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == ' ':
+			inFields = true
+		case c == '"':
+			inQuote = inFields && !inQuote
+		case c == '\n':
+			if !inQuote {
+				sp.lineNo = start
+				return b.String(), nil
+			}
+		}
+		b.WriteByte(c)
+	}
+}
+
+// lineScanner walks a line of line protocol.
+type lineScanner struct {
+	line string
+	pos  int
+}
+
+// until returns the text up to the first unescaped byte of stops and leaves
+// the position at that byte.
+func (s *lineScanner) until(stops string) string {
+	start := s.pos
+	for s.pos < len(s.line) {
+		c := s.line[s.pos]
+		if c == '\\' && s.pos+1 < len(s.line) {
+			s.pos += 2
+			continue
+		}
+		if strings.IndexByte(stops, c) >= 0 {
+			break
+		}
+		s.pos++
+	}
+	return s.line[start:s.pos]
+}
+
+func (s *lineScanner) peek() byte {
+	if s.pos >= len(s.line) {
+		return 0
+	}
+	return s.line[s.pos]
+}
+
+func (s *lineScanner) expect(c byte) error {
+	if s.peek() != c {
+		if s.pos >= len(s.line) {
+			return fmt.Errorf("expected %q, found end of line", c)
+		}
+		return fmt.Errorf("expected %q at column %d", c, s.pos+1)
+	}
+	s.pos++
+	return nil
+}
+
+func (p *Parser) parseLine(line string) (internal.Metric, error) {
+	s := &lineScanner{line: line}
+
+	name := unescape(s.until(", "))
+	if name == "" {
+		return nil, fmt.Errorf("missing measurement")
+	}
+
+	tags := make(map[string]string, len(p.DefaultTags))
+	for s.peek() == ',' {
+		s.pos++
+		key := unescape(s.until("=, "))
+		if err := s.expect('='); err != nil {
+			return nil, err
+		}
+		value := unescape(s.until(", "))
+		if key == "" || value == "" {
+			return nil, fmt.Errorf("invalid tag at column %d", s.pos)
+		}
+		tags[key] = value
+	}
+	for k, v := range p.DefaultTags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+
+	if err := s.expect(' '); err != nil {
+		return nil, fmt.Errorf("missing fields")
+	}
+
+	fields := make(map[string]interface{})
+	for {
+		key := unescape(s.until("= "))
+		if key == "" {
+			return nil, fmt.Errorf("missing field key at column %d", s.pos+1)
+		}
+		if err := s.expect('='); err != nil {
+			return nil, err
+		}
+
+		value, err := s.fieldValue()
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", key, err)
+		}
+		fields[key] = value
+
+		if s.peek() != ',' {
+			break
+		}
+		s.pos++
+	}
+
+	tm := p.TimeFunc
+	if tm == nil {
+		tm = time.Now
+	}
+	timestamp := tm()
+	if s.peek() == ' ' {
+		s.pos++
+		ts := strings.TrimSpace(s.line[s.pos:])
+		if ts != "" {
+			ns, err := strconv.ParseInt(ts, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp %q", ts)
+			}
+			timestamp = time.Unix(0, ns)
+		}
+	} else if s.pos < len(s.line) {
+		return nil, fmt.Errorf("unexpected %q at column %d", s.peek(), s.pos+1)
+	}
+
+	return metric.New(name, tags, fields, timestamp)
+}
+
+// fieldValue parses a field value starting at the current position.
+func (s *lineScanner) fieldValue() (interface{}, error) {
+	if s.peek() == '"' {
+		s.pos++
+		var b strings.Builder
+		for s.pos < len(s.line) {
+			c := s.line[s.pos]
+			switch {
+			case c == '\\' && s.pos+1 < len(s.line) &&
+				(s.line[s.pos+1] == '"' || s.line[s.pos+1] == '\\'):
+				b.WriteByte(s.line[s.pos+1])
+				s.pos += 2
+			case c == '"':
+				s.pos++
+				return b.String(), nil
+			default:
+				b.WriteByte(c)
+				s.pos++
+			}
+		}
+		return nil, fmt.Errorf("unterminated string")
+	}
+
+	raw := s.until(", ")
+	switch raw {
+	case "":
+		return nil, fmt.Errorf("missing value")
+	case "t", "T", "true", "True", "TRUE":
+		return true, nil
+	case "f", "F", "false", "False", "FALSE":
+		return false, nil
+	}
+
+	switch raw[len(raw)-1] {
+	case 'i':
+		return strconv.ParseInt(raw[:len(raw)-1], 10, 64)
+	case 'u':
+		return strconv.ParseUint(raw[:len(raw)-1], 10, 64)
+	}
+	return strconv.ParseFloat(raw, 64)
+}
+
+// unescape reverses the escaping of measurements, tag keys and values, and
+// field keys.  The serializer writes control characters as "\t", "\n", "\f"
+// and "\r" but does not escape backslashes, so a backslash followed by one of
+// these letters is always read as the control character.
+func unescape(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+
+		switch n := s[i+1]; n {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case ',', ' ', '=', '\\', '"':
+			b.WriteByte(n)
+		default:
+			b.WriteByte(c)
+			b.WriteByte(n)
+		}
+		i++
+	}
+	return b.String()
+}
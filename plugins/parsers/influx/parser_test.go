@@ -0,0 +1,107 @@
+package influx
+
+import (
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/plugins/serializers/influx"
+	"github.com/geekflow/straw/testutil"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	parser := NewParser()
+	metrics, err := parser.Parse([]byte(
+		"# comment\n" +
+			"cpu,cpu=cpu0,host=localhost usage_idle=99.5,count=42i,big=18446744073709551615u,up=true,state=\"ok\" 1000000000\n" +
+			"\n" +
+			"mem free=1i 2000000000\n"))
+	require.NoError(t, err)
+
+	expected := []internal.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"cpu": "cpu0", "host": "localhost"},
+			map[string]interface{}{
+				"usage_idle": 99.5,
+				"count":      int64(42),
+				"big":        uint64(18446744073709551615),
+				"up":         true,
+				"state":      "ok",
+			},
+			time.Unix(1, 0)),
+		testutil.MustMetric("mem",
+			map[string]string{},
+			map[string]interface{}{"free": int64(1)},
+			time.Unix(2, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseDefaultTime(t *testing.T) {
+	parser := NewParser()
+	parser.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+
+	m, err := parser.ParseLine("cpu value=1")
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(42, 0), m.Time())
+}
+
+func TestParseErrors(t *testing.T) {
+	parser := NewParser()
+	for _, line := range []string{
+		"cpu",
+		"cpu value",
+		"cpu value=",
+		"cpu,host value=1",
+		"cpu value=\"open",
+		"cpu value=1 now",
+		"cpu value=1x",
+	} {
+		_, err := parser.ParseLine(line)
+		require.Error(t, err, line)
+	}
+}
+
+func TestStreamParserResumesAfterError(t *testing.T) {
+	sp := NewParser().NewStreamParser(strings.NewReader("cpu value=1 1\ncpu value=\ncpu value=3 3"))
+
+	m, err := sp.Next()
+	require.NoError(t, err)
+	require.Equal(t, 1.0, m.Fields()["value"])
+
+	_, err = sp.Next()
+	require.Error(t, err)
+	require.Equal(t, 2, err.(*ParseError).LineNumber)
+
+	m, err = sp.Next()
+	require.NoError(t, err)
+	require.Equal(t, 3.0, m.Fields()["value"])
+
+	_, err = sp.Next()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestSerializerRoundTrip(t *testing.T) {
+	metrics := []internal.Metric{
+		testutil.MustMetric("cpu usage,total",
+			map[string]string{"host name": "a,b=c", "path": "C:\\data"},
+			map[string]interface{}{
+				"message":  "line one\nline \"two\" \\",
+				"value":    1.5,
+				"tab\tkey": int64(-3),
+			},
+			time.Unix(0, 1234)),
+	}
+
+	serializer := influx.NewSerializer()
+	serializer.SetFieldTypeSupport(influx.UintSupport)
+	buf, err := serializer.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	parsed, err := NewParser().Parse(buf)
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, metrics, parsed)
+}
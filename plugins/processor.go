@@ -0,0 +1,16 @@
+package plugins
+
+import (
+	"github.com/geekflow/straw/internal"
+)
+
+// Processor transforms metrics between an input and the outputs.
+type Processor interface {
+	SampleConfig() string
+	Description() string
+
+	// Apply transforms the metrics and returns the metrics to pass on.  It
+	// may modify, add or remove metrics; metrics that are removed must be
+	// dropped with Drop.
+	Apply(in ...internal.Metric) []internal.Metric
+}
@@ -0,0 +1,11 @@
+package all
+
+import (
+	_ "github.com/geekflow/straw/plugins/processors/converter"
+	_ "github.com/geekflow/straw/plugins/processors/dedup"
+	_ "github.com/geekflow/straw/plugins/processors/expression"
+	_ "github.com/geekflow/straw/plugins/processors/lookup"
+	_ "github.com/geekflow/straw/plugins/processors/rate"
+	_ "github.com/geekflow/straw/plugins/processors/regex"
+	_ "github.com/geekflow/straw/plugins/processors/script"
+)
@@ -0,0 +1,412 @@
+package converter
+
+import (
+	"fmt"
+	"github.com/geekflow/straw/filter"
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/processors"
+	"math"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var sampleConfig = `
+  ## Tags to convert.  The value of each key is a list of tag keys, which may
+  ## contain glob patterns.  A tag converted to a field is removed.
+  [inputs.process.processors.converter.tags]
+    measurement = []
+    string = []
+    integer = []
+    unsigned = []
+    boolean = []
+    float = []
+
+  ## Fields to convert.  The value of each key is a list of field keys,
+  ## which may contain glob patterns.  A field converted to a tag or to the
+  ## measurement name is removed.
+  [inputs.process.processors.converter.fields]
+    measurement = []
+    tag = ["status"]
+    string = []
+    integer = []
+    unsigned = []
+    boolean = []
+    float = []
+`
+
+// Conversion lists the tag or field keys to convert to each type.
+type Conversion struct {
+	Measurement []string `toml:"measurement"`
+	Tag         []string `toml:"tag"`
+	String      []string `toml:"string"`
+	Integer     []string `toml:"integer"`
+	Unsigned    []string `toml:"unsigned"`
+	Boolean     []string `toml:"boolean"`
+	Float       []string `toml:"float"`
+}
+
+// Converter changes the type of fields and moves values between tags,
+// fields and the measurement name.
+type Converter struct {
+	Tags   *Conversion `toml:"tags"`
+	Fields *Conversion `toml:"fields"`
+
+	tagConversions   *conversionFilter
+	fieldConversions *conversionFilter
+}
+
+// conversionFilter holds the compiled key filters of a Conversion.
+type conversionFilter struct {
+	Measurement filter.Filter
+	Tag         filter.Filter
+	String      filter.Filter
+	Integer     filter.Filter
+	Unsigned    filter.Filter
+	Boolean     filter.Filter
+	Float       filter.Filter
+}
+
+func (*Converter) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Converter) Description() string {
+	return "Convert values to another metric value type"
+}
+
+func (c *Converter) Init() error {
+	var err error
+	c.tagConversions, err = compileFilter(c.Tags)
+	if err != nil {
+		return err
+	}
+	c.fieldConversions, err = compileFilter(c.Fields)
+	return err
+}
+
+func compileFilter(conv *Conversion) (*conversionFilter, error) {
+	if conv == nil {
+		return nil, nil
+	}
+
+	var err error
+	cf := &conversionFilter{}
+	for _, f := range []struct {
+		dst  *filter.Filter
+		keys []string
+	}{
+		{&cf.Measurement, conv.Measurement},
+		{&cf.Tag, conv.Tag},
+		{&cf.String, conv.String},
+		{&cf.Integer, conv.Integer},
+		{&cf.Unsigned, conv.Unsigned},
+		{&cf.Boolean, conv.Boolean},
+		{&cf.Float, conv.Float},
+	} {
+		*f.dst, err = filter.Compile(f.keys)
+		if err != nil {
+			return nil, fmt.Errorf("converter: %v", err)
+		}
+	}
+	return cf, nil
+}
+
+func (c *Converter) Apply(in ...internal.Metric) []internal.Metric {
+	out := in[:0]
+	for _, m := range in {
+		if c.tagConversions != nil {
+			c.convertTags(m)
+		}
+		if c.fieldConversions != nil {
+			c.convertFields(m)
+		}
+
+		if len(m.FieldList()) == 0 {
+			m.Drop()
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func match(f filter.Filter, key string) bool {
+	return f != nil && f.Match(key)
+}
+
+func (c *Converter) convertTags(m internal.Metric) {
+	for _, tag := range copyTags(m) {
+		key, value := tag.Key, tag.Value
+
+		switch cf := c.tagConversions; {
+		case match(cf.Measurement, key):
+			m.RemoveTag(key)
+			m.SetName(value)
+		case match(cf.String, key):
+			m.RemoveTag(key)
+			m.AddField(key, value)
+		case match(cf.Integer, key):
+			moveTag(m, key, value, toInteger)
+		case match(cf.Unsigned, key):
+			moveTag(m, key, value, toUnsigned)
+		case match(cf.Boolean, key):
+			moveTag(m, key, value, toBool)
+		case match(cf.Float, key):
+			moveTag(m, key, value, toFloat)
+		}
+	}
+}
+
+// moveTag replaces the tag with a field of the converted value.  The tag is
+// kept if the value cannot be converted.
+func moveTag(
+	m internal.Metric,
+	key string,
+	value string,
+	convert func(interface{}) (interface{}, bool),
+) {
+	v, ok := convert(value)
+	if !ok {
+		log.Debugf("[processors.converter] Could not convert tag %q value %q", key, value)
+		return
+	}
+	m.RemoveTag(key)
+	m.AddField(key, v)
+}
+
+func (c *Converter) convertFields(m internal.Metric) {
+	for _, field := range copyFields(m) {
+		key, value := field.Key, field.Value
+
+		switch cf := c.fieldConversions; {
+		case match(cf.Measurement, key):
+			m.RemoveField(key)
+			if v, ok := stringValue(value); ok && v != "" {
+				m.SetName(v)
+			}
+		case match(cf.Tag, key):
+			m.RemoveField(key)
+			if v, ok := stringValue(value); ok && v != "" {
+				m.AddTag(key, v)
+			}
+		case match(cf.String, key):
+			convertField(m, key, value, toString)
+		case match(cf.Integer, key):
+			convertField(m, key, value, toInteger)
+		case match(cf.Unsigned, key):
+			convertField(m, key, value, toUnsigned)
+		case match(cf.Boolean, key):
+			convertField(m, key, value, toBool)
+		case match(cf.Float, key):
+			convertField(m, key, value, toFloat)
+		}
+	}
+}
+
+// convertField replaces the field value with the converted value.  The
+// field is removed if the value cannot be converted, so that the field
+// always has the configured type.
+func convertField(
+	m internal.Metric,
+	key string,
+	value interface{},
+	convert func(interface{}) (interface{}, bool),
+) {
+	v, ok := convert(value)
+	if !ok {
+		log.Debugf("[processors.converter] Could not convert field %q value %v", key, value)
+		m.RemoveField(key)
+		return
+	}
+	m.AddField(key, v)
+}
+
+// copyTags returns the tags of the metric, so that they can be changed
+// while iterating.
+func copyTags(m internal.Metric) []internal.Tag {
+	tags := make([]internal.Tag, 0, len(m.TagList()))
+	for _, tag := range m.TagList() {
+		tags = append(tags, *tag)
+	}
+	return tags
+}
+
+func copyFields(m internal.Metric) []internal.Field {
+	fields := make([]internal.Field, 0, len(m.FieldList()))
+	for _, field := range m.FieldList() {
+		fields = append(fields, *field)
+	}
+	return fields
+}
+
+func toString(v interface{}) (interface{}, bool) {
+	return stringValue(v)
+}
+
+func stringValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+func toInteger(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, true
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v), true
+		}
+	case float64:
+		if v >= math.MinInt64 && v < math.MaxInt64 {
+			return int64(v), true
+		}
+	case bool:
+		if v {
+			return int64(1), true
+		}
+		return int64(0), true
+	case string:
+		if i, err := parseInt(v); err == nil {
+			return i, true
+		}
+		if f, ok := parseFloat(v); ok {
+			return toInteger(f)
+		}
+	}
+	return nil, false
+}
+
+func toUnsigned(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case int64:
+		if v >= 0 {
+			return uint64(v), true
+		}
+	case uint64:
+		return v, true
+	case float64:
+		if v >= 0 && v < math.MaxUint64 {
+			return uint64(v), true
+		}
+	case bool:
+		if v {
+			return uint64(1), true
+		}
+		return uint64(0), true
+	case string:
+		if u, err := parseUint(v); err == nil {
+			return u, true
+		}
+		if f, ok := parseFloat(v); ok {
+			return toUnsigned(f)
+		}
+	}
+	return nil, false
+}
+
+func toFloat(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return float64(1), true
+		}
+		return float64(0), true
+	case string:
+		if f, ok := parseFloat(v); ok {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+func toBool(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v != 0, true
+	case uint64:
+		return v != 0, true
+	case float64:
+		return v != 0, true
+	case bool:
+		return v, true
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// parseInt parses a decimal integer, or a hexadecimal one with a "0x"
+// prefix.  A leading zero does not make the number octal and underscores
+// are not accepted.
+func parseInt(s string) (int64, error) {
+	sign := ""
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+	if hex, ok := trimHexPrefix(s); ok {
+		return strconv.ParseInt(sign+hex, 16, 64)
+	}
+	return strconv.ParseInt(sign+s, 10, 64)
+}
+
+// parseUint parses an unsigned integer like parseInt.
+func parseUint(s string) (uint64, error) {
+	if hex, ok := trimHexPrefix(s); ok {
+		return strconv.ParseUint(hex, 16, 64)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func trimHexPrefix(s string) (string, bool) {
+	if len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		return s[2:], true
+	}
+	return s, false
+}
+
+// parseFloat parses decimal and hexadecimal numbers, which may be followed
+// by a percent sign.
+func parseFloat(s string) (float64, bool) {
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "%"))
+	if strings.Contains(s, "_") {
+		return 0, false
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, true
+	}
+	if i, err := parseInt(s); err == nil {
+		return float64(i), true
+	}
+	if u, err := parseUint(s); err == nil {
+		return float64(u), true
+	}
+	return 0, false
+}
+
+func init() {
+	processors.Add("converter", func() plugins.Processor {
+		return &Converter{}
+	})
+}
@@ -0,0 +1,141 @@
+package converter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func apply(t *testing.T, c *Converter, m internal.Metric) []internal.Metric {
+	require.NoError(t, c.Init())
+	return c.Apply(m)
+}
+
+func TestConvertFields(t *testing.T) {
+	c := &Converter{Fields: &Conversion{
+		Integer:  []string{"hex", "pct_int", "bool"},
+		Unsigned: []string{"negative", "count"},
+		Float:    []string{"pct", "int_*"},
+		Boolean:  []string{"flag"},
+		String:   []string{"uptime"},
+	}}
+
+	m := testutil.MustMetric("test", nil,
+		map[string]interface{}{
+			"hex":       "0x1F",
+			"pct_int":   "12.5%",
+			"bool":      true,
+			"negative":  int64(-1),
+			"count":     "42",
+			"pct":       "12.5%",
+			"int_a":     int64(3),
+			"int_b":     uint64(4),
+			"flag":      "true",
+			"uptime":    int64(3600),
+			"untouched": "x",
+		},
+		time.Unix(0, 0))
+
+	out := apply(t, c, m)
+	require.Len(t, out, 1)
+	require.Equal(t, map[string]interface{}{
+		"hex":       int64(31),
+		"pct_int":   int64(12),
+		"bool":      int64(1),
+		"count":     uint64(42),
+		"pct":       12.5,
+		"int_a":     3.0,
+		"int_b":     4.0,
+		"flag":      true,
+		"uptime":    "3600",
+		"untouched": "x",
+	}, out[0].Fields())
+}
+
+func TestParseNumbers(t *testing.T) {
+	c := &Converter{Fields: &Conversion{
+		Integer:  []string{"int_*"},
+		Unsigned: []string{"uint_*"},
+		Float:    []string{"float_*"},
+	}}
+
+	m := testutil.MustMetric("test", nil,
+		map[string]interface{}{
+			"int_leading_zero":     "010",
+			"int_hex":              "-0x10",
+			"int_underscore":       "1_000",
+			"int_binary":           "0b1",
+			"uint_leading_zero":    "010",
+			"uint_hex":             "0XfF",
+			"uint_underscore":      "1_000",
+			"uint_octal":           "0o7",
+			"float_leading_zero":   "010",
+			"float_underscore":     "1_000",
+			"float_hex_underscore": "0x1_0",
+		},
+		time.Unix(0, 0))
+
+	out := apply(t, c, m)
+	require.Len(t, out, 1)
+	require.Equal(t, map[string]interface{}{
+		"int_leading_zero":   int64(10),
+		"int_hex":            int64(-16),
+		"uint_leading_zero":  uint64(10),
+		"uint_hex":           uint64(255),
+		"float_leading_zero": 10.0,
+	}, out[0].Fields())
+}
+
+func TestFieldsToTagsAndMeasurement(t *testing.T) {
+	c := &Converter{Fields: &Conversion{
+		Tag:         []string{"status"},
+		Measurement: []string{"name"},
+	}}
+
+	m := testutil.MustMetric("process",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"status": "S", "name": "proc", "cpu": 1.5},
+		time.Unix(0, 0))
+
+	out := apply(t, c, m)
+	require.Len(t, out, 1)
+	require.Equal(t, "proc", out[0].Name())
+	require.Equal(t, map[string]string{"host": "a", "status": "S"}, out[0].Tags())
+	require.Equal(t, map[string]interface{}{"cpu": 1.5}, out[0].Fields())
+}
+
+func TestTagsToFields(t *testing.T) {
+	c := &Converter{Tags: &Conversion{
+		Integer: []string{"port"},
+		String:  []string{"path"},
+		Float:   []string{"bad"},
+	}}
+
+	m := testutil.MustMetric("http",
+		map[string]string{"port": "8080", "path": "/", "bad": "x"},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(0, 0))
+
+	out := apply(t, c, m)
+	require.Len(t, out, 1)
+	// Tags that cannot be converted are kept.
+	require.Equal(t, map[string]string{"bad": "x"}, out[0].Tags())
+	require.Equal(t, map[string]interface{}{
+		"value": 1.0,
+		"port":  int64(8080),
+		"path":  "/",
+	}, out[0].Fields())
+}
+
+func TestDropMetricWithoutFields(t *testing.T) {
+	c := &Converter{Fields: &Conversion{Tag: []string{"*"}}}
+
+	m := testutil.MustMetric("process", nil,
+		map[string]interface{}{"status": "R"},
+		time.Unix(0, 0))
+
+	require.Len(t, apply(t, c, m), 0)
+}
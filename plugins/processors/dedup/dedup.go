@@ -0,0 +1,192 @@
+package dedup
+
+import (
+	"fmt"
+	"github.com/geekflow/straw/filter"
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/processors"
+	"time"
+)
+
+var sampleConfig = `
+  ## Longest time a value is suppressed; an unchanged value is passed on at
+  ## least once per interval.
+  dedup_interval = "10m"
+
+  ## What is suppressed when unchanged, one of:
+  ##   "metric" - the metric, if all of its fields are unchanged
+  ##   "field"  - each field matching fields; the metric is dropped if no
+  ##              fields remain
+  # mode = "metric"
+  # fields = ["*"]
+`
+
+// Dedup suppresses values that have not changed since they were last passed
+// on for the same series.
+type Dedup struct {
+	DedupInterval internal.Duration `toml:"dedup_interval"`
+	Mode          string            `toml:"mode"`
+	Fields        []string          `toml:"fields"`
+
+	perField  bool
+	filter    filter.Filter
+	cache     map[uint64]*entry
+	lastPurge time.Time
+}
+
+// entry holds the last values passed on for a series.
+type entry struct {
+	// time the metric was last passed on, in metric mode.
+	time   time.Time
+	fields map[string]value
+}
+
+type value struct {
+	value interface{}
+	time  time.Time
+}
+
+func (*Dedup) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Dedup) Description() string {
+	return "Filter metrics with repeating field values"
+}
+
+func (d *Dedup) Init() error {
+	if d.DedupInterval.Duration <= 0 {
+		return fmt.Errorf("dedup: dedup_interval must be positive")
+	}
+
+	switch d.Mode {
+	case "", "metric":
+	case "field":
+		d.perField = true
+	default:
+		return fmt.Errorf("dedup: invalid mode %q", d.Mode)
+	}
+
+	fields := d.Fields
+	if len(fields) == 0 {
+		fields = []string{"*"}
+	}
+
+	var err error
+	d.filter, err = filter.Compile(fields)
+	if err != nil {
+		return fmt.Errorf("dedup: %v", err)
+	}
+
+	d.cache = make(map[uint64]*entry)
+	return nil
+}
+
+func (d *Dedup) Apply(in ...internal.Metric) []internal.Metric {
+	out := in[:0]
+	for _, m := range in {
+		var keep bool
+		if d.perField {
+			keep = d.applyFields(m)
+		} else {
+			keep = d.applyMetric(m)
+		}
+
+		if !keep {
+			m.Drop()
+			continue
+		}
+		out = append(out, m)
+	}
+
+	if len(in) > 0 {
+		d.purge(in[len(in)-1].Time())
+	}
+	return out
+}
+
+// applyMetric reports whether the metric has changed or is due.
+func (d *Dedup) applyMetric(m internal.Metric) bool {
+	id := m.HashID()
+	now := m.Time()
+
+	e, ok := d.cache[id]
+	if ok && now.Sub(e.time) < d.DedupInterval.Duration && sameFields(e, m) {
+		return false
+	}
+
+	e = &entry{time: now, fields: make(map[string]value, len(m.FieldList()))}
+	for _, field := range m.FieldList() {
+		e.fields[field.Key] = value{value: field.Value, time: now}
+	}
+	d.cache[id] = e
+	return true
+}
+
+func sameFields(e *entry, m internal.Metric) bool {
+	if len(e.fields) != len(m.FieldList()) {
+		return false
+	}
+	for _, field := range m.FieldList() {
+		last, ok := e.fields[field.Key]
+		if !ok || last.value != field.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// applyFields removes the unchanged fields that are not due and reports
+// whether any fields remain.
+func (d *Dedup) applyFields(m internal.Metric) bool {
+	id := m.HashID()
+	now := m.Time()
+
+	e, ok := d.cache[id]
+	if !ok {
+		e = &entry{fields: make(map[string]value, len(m.FieldList()))}
+		d.cache[id] = e
+	}
+	e.time = now
+
+	var unchanged []string
+	for _, field := range m.FieldList() {
+		if !d.filter.Match(field.Key) {
+			continue
+		}
+
+		last, ok := e.fields[field.Key]
+		if ok && last.value == field.Value && now.Sub(last.time) < d.DedupInterval.Duration {
+			unchanged = append(unchanged, field.Key)
+			continue
+		}
+		e.fields[field.Key] = value{value: field.Value, time: now}
+	}
+
+	for _, key := range unchanged {
+		m.RemoveField(key)
+	}
+	return len(m.FieldList()) > 0
+}
+
+// purge forgets the series not seen within the dedup interval, their next
+// metric is passed on anyway.
+func (d *Dedup) purge(now time.Time) {
+	if now.Sub(d.lastPurge) < d.DedupInterval.Duration {
+		return
+	}
+
+	for id, e := range d.cache {
+		if now.Sub(e.time) >= d.DedupInterval.Duration {
+			delete(d.cache, id)
+		}
+	}
+	d.lastPurge = now
+}
+
+func init() {
+	processors.Add("dedup", func() plugins.Processor {
+		return &Dedup{DedupInterval: internal.Duration{Duration: 10 * time.Minute}}
+	})
+}
@@ -0,0 +1,94 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(fields map[string]interface{}, sec int64) internal.Metric {
+	return testutil.MustMetric("mem",
+		map[string]string{"host": "web01"},
+		fields,
+		time.Unix(sec, 0))
+}
+
+func newDedup(t *testing.T, mode string) *Dedup {
+	d := &Dedup{
+		DedupInterval: internal.Duration{Duration: time.Minute},
+		Mode:          mode,
+	}
+	require.NoError(t, d.Init())
+	return d
+}
+
+func TestDedupMetric(t *testing.T) {
+	d := newDedup(t, "metric")
+
+	fields := map[string]interface{}{"total": uint64(1024), "used": uint64(512)}
+	require.Len(t, d.Apply(newMetric(fields, 0)), 1)
+	require.Len(t, d.Apply(newMetric(fields, 10)), 0)
+	require.Len(t, d.Apply(newMetric(fields, 50)), 0)
+
+	// Heartbeat.
+	require.Len(t, d.Apply(newMetric(fields, 60)), 1)
+
+	// Any changed field passes the whole metric.
+	out := d.Apply(newMetric(map[string]interface{}{"total": uint64(1024), "used": uint64(600)}, 70))
+	require.Len(t, out, 1)
+	require.Len(t, out[0].FieldList(), 2)
+
+	// So does a missing field.
+	require.Len(t, d.Apply(newMetric(map[string]interface{}{"total": uint64(1024)}, 80)), 1)
+}
+
+func TestDedupField(t *testing.T) {
+	d := newDedup(t, "field")
+
+	out := d.Apply(newMetric(map[string]interface{}{"total": uint64(1024), "used": uint64(512)}, 0))
+	require.Len(t, out, 1)
+	require.Len(t, out[0].FieldList(), 2)
+
+	out = d.Apply(newMetric(map[string]interface{}{"total": uint64(1024), "used": uint64(600)}, 10))
+	require.Len(t, out, 1)
+	require.Equal(t, map[string]interface{}{"used": uint64(600)}, out[0].Fields())
+
+	require.Len(t, d.Apply(newMetric(map[string]interface{}{"total": uint64(1024), "used": uint64(600)}, 20)), 0)
+
+	// total is due again, used was last passed on at 10s.
+	out = d.Apply(newMetric(map[string]interface{}{"total": uint64(1024), "used": uint64(600)}, 60))
+	require.Len(t, out, 1)
+	require.Equal(t, map[string]interface{}{"total": uint64(1024)}, out[0].Fields())
+}
+
+func TestDedupFieldFilter(t *testing.T) {
+	d := &Dedup{
+		DedupInterval: internal.Duration{Duration: time.Minute},
+		Mode:          "field",
+		Fields:        []string{"rlimit_*"},
+	}
+	require.NoError(t, d.Init())
+
+	fields := map[string]interface{}{"rlimit_num_fds": int64(1024), "cpu_usage": 1.0}
+	d.Apply(newMetric(fields, 0))
+	out := d.Apply(newMetric(fields, 10))
+	require.Len(t, out, 1)
+	require.Equal(t, map[string]interface{}{"cpu_usage": 1.0}, out[0].Fields())
+}
+
+func TestDedupPurge(t *testing.T) {
+	d := newDedup(t, "metric")
+
+	fields := map[string]interface{}{"total": uint64(1024)}
+	d.Apply(newMetric(fields, 0))
+	d.Apply(testutil.MustMetric("mem", map[string]string{"host": "web02"}, fields, time.Unix(120, 0)))
+	require.Len(t, d.cache, 1)
+}
+
+func TestDedupInit(t *testing.T) {
+	require.Error(t, (&Dedup{}).Init())
+	require.Error(t, (&Dedup{DedupInterval: internal.Duration{Duration: time.Minute}, Mode: "x"}).Init())
+}
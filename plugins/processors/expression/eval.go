@@ -0,0 +1,354 @@
+package expression
+
+import (
+	"errors"
+	"fmt"
+	"github.com/geekflow/straw/internal"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	errDivisionByZero = errors.New("division by zero")
+	errOverflow       = errors.New("integer overflow")
+)
+
+// number is the value of an expression.  Integer operands give an integer
+// result except for division, any float operand gives a float.
+type number struct {
+	isInt bool
+	i     int64
+	f     float64
+}
+
+func intNumber(i int64) number     { return number{isInt: true, i: i} }
+func floatNumber(f float64) number { return number{f: f} }
+
+func (n number) float() float64 {
+	if n.isInt {
+		return float64(n.i)
+	}
+	return n.f
+}
+
+func (n number) value() interface{} {
+	if n.isInt {
+		return n.i
+	}
+	return n.f
+}
+
+type node interface {
+	eval(m internal.Metric) (number, error)
+}
+
+type literal struct {
+	n number
+}
+
+type field struct {
+	name string
+}
+
+type unary struct {
+	x node
+}
+
+type binary struct {
+	op   byte
+	x, y node
+}
+
+func (l *literal) eval(internal.Metric) (number, error) {
+	return l.n, nil
+}
+
+// eval returns the value of the field.  Unsigned values too large for an
+// int64 are converted to floats.
+func (f *field) eval(m internal.Metric) (number, error) {
+	v, ok := m.GetField(f.name)
+	if !ok {
+		return number{}, fmt.Errorf("missing field %s", f.name)
+	}
+	switch v := v.(type) {
+	case int64:
+		return intNumber(v), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return floatNumber(float64(v)), nil
+		}
+		return intNumber(int64(v)), nil
+	case float64:
+		return floatNumber(v), nil
+	}
+	return number{}, fmt.Errorf("field %s is not numeric", f.name)
+}
+
+func (u *unary) eval(m internal.Metric) (number, error) {
+	x, err := u.x.eval(m)
+	if err != nil {
+		return number{}, err
+	}
+	if !x.isInt {
+		return floatNumber(-x.f), nil
+	}
+	if x.i == math.MinInt64 {
+		return number{}, errOverflow
+	}
+	return intNumber(-x.i), nil
+}
+
+func (b *binary) eval(m internal.Metric) (number, error) {
+	x, err := b.x.eval(m)
+	if err != nil {
+		return number{}, err
+	}
+	y, err := b.y.eval(m)
+	if err != nil {
+		return number{}, err
+	}
+
+	if b.op == '/' || b.op == '%' {
+		if y.float() == 0 {
+			return number{}, errDivisionByZero
+		}
+	}
+
+	if x.isInt && y.isInt && b.op != '/' {
+		return intOp(b.op, x.i, y.i)
+	}
+
+	xf, yf := x.float(), y.float()
+	var r float64
+	switch b.op {
+	case '+':
+		r = xf + yf
+	case '-':
+		r = xf - yf
+	case '*':
+		r = xf * yf
+	case '/':
+		r = xf / yf
+	case '%':
+		r = math.Mod(xf, yf)
+	}
+	if math.IsInf(r, 0) || math.IsNaN(r) {
+		return number{}, fmt.Errorf("result is not finite")
+	}
+	return floatNumber(r), nil
+}
+
+func intOp(op byte, x, y int64) (number, error) {
+	switch op {
+	case '+':
+		r := x + y
+		if (r > x) != (y > 0) {
+			return number{}, errOverflow
+		}
+		return intNumber(r), nil
+	case '-':
+		r := x - y
+		if (r < x) != (y > 0) {
+			return number{}, errOverflow
+		}
+		return intNumber(r), nil
+	case '*':
+		if x == 0 || y == 0 {
+			return intNumber(0), nil
+		}
+		r := x * y
+		if r/y != x || x == -1 && y == math.MinInt64 || y == -1 && x == math.MinInt64 {
+			return number{}, errOverflow
+		}
+		return intNumber(r), nil
+	}
+	if x == math.MinInt64 && y == -1 {
+		return intNumber(0), nil
+	}
+	return intNumber(x % y), nil
+}
+
+// parseAssignment parses "field = expression".
+func parseAssignment(src string) (*assignment, error) {
+	eq := strings.Index(src, "=")
+	if eq < 0 {
+		return nil, fmt.Errorf("expected field = expression")
+	}
+	name := strings.TrimSpace(src[:eq])
+	if !isName(name) {
+		return nil, fmt.Errorf("invalid field name %q", name)
+	}
+
+	p := &parser{src: src[eq+1:]}
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.i < len(p.src) {
+		return nil, fmt.Errorf("unexpected %q", p.src[p.i:])
+	}
+	return &assignment{field: name, expr: expr}, nil
+}
+
+// parser is a recursive descent parser for expressions with the usual
+// precedence: unary minus, then * / %, then + -.
+type parser struct {
+	src string
+	i   int
+}
+
+func (p *parser) skipSpace() {
+	for p.i < len(p.src) && (p.src[p.i] == ' ' || p.src[p.i] == '\t') {
+		p.i++
+	}
+}
+
+// peek returns the next character that is not a space, or 0 at the end.
+func (p *parser) peek() byte {
+	p.skipSpace()
+	if p.i < len(p.src) {
+		return p.src[p.i]
+	}
+	return 0
+}
+
+func (p *parser) parseExpr() (node, error) {
+	x, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return x, nil
+		}
+		p.i++
+		y, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		x = &binary{op: op, x: x, y: y}
+	}
+}
+
+func (p *parser) parseTerm() (node, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return x, nil
+		}
+		p.i++
+		y, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		x = &binary{op: op, x: x, y: y}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	switch p.peek() {
+	case '-':
+		p.i++
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{x: x}, nil
+	case '+':
+		p.i++
+		return p.parseUnary()
+	}
+	return p.parseOperand()
+}
+
+func (p *parser) parseOperand() (node, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	case c == '(':
+		p.i++
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.i++
+		return x, nil
+	case isDigit(c) || c == '.':
+		return p.parseNumber()
+	case isNameStart(c):
+		start := p.i
+		for p.i < len(p.src) && isNameChar(p.src[p.i]) {
+			p.i++
+		}
+		return &field{name: p.src[start:p.i]}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", p.src[p.i:])
+}
+
+func (p *parser) parseNumber() (node, error) {
+	start := p.i
+	isFloat := false
+	for p.i < len(p.src) {
+		c := p.src[p.i]
+		if c == 'e' || c == 'E' {
+			isFloat = true
+			if p.i+1 < len(p.src) && (p.src[p.i+1] == '+' || p.src[p.i+1] == '-') {
+				p.i++
+			}
+		} else if c == '.' {
+			isFloat = true
+		} else if !isDigit(c) {
+			break
+		}
+		p.i++
+	}
+
+	text := p.src[start:p.i]
+	if isFloat {
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", text)
+		}
+		return &literal{n: floatNumber(f)}, nil
+	}
+	i, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", text)
+	}
+	return &literal{n: intNumber(i)}, nil
+}
+
+func isName(s string) bool {
+	if s == "" || !isNameStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isNameChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || isDigit(c) || c == '.'
+}
@@ -0,0 +1,93 @@
+package expression
+
+import (
+	"fmt"
+	"github.com/geekflow/straw/filter"
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/processors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var sampleConfig = `
+  ## Measurements the expressions apply to, glob patterns are supported.  By
+  ## default they apply to all metrics.
+  # measurements = ["mem"]
+
+  ## Fields to compute, in order; later expressions may use the results of
+  ## earlier ones.  Expressions use fields, numbers, parentheses and the
+  ## operators + - * / %.  A field is not set if a field the expression uses
+  ## is missing or not numeric, or on division by zero.
+  expressions = [
+    "used_ratio = used / total",
+  ]
+`
+
+// Expression adds fields computed from the other fields of a metric.
+type Expression struct {
+	Measurements []string `toml:"measurements"`
+	Expressions  []string `toml:"expressions"`
+
+	filter      filter.Filter
+	assignments []*assignment
+}
+
+// assignment is a parsed expression and the field it sets.
+type assignment struct {
+	field string
+	expr  node
+}
+
+func (*Expression) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Expression) Description() string {
+	return "Compute fields with arithmetic expressions"
+}
+
+func (e *Expression) Init() error {
+	if len(e.Expressions) == 0 {
+		return fmt.Errorf("expression: no expressions")
+	}
+
+	var err error
+	e.filter, err = filter.Compile(e.Measurements)
+	if err != nil {
+		return fmt.Errorf("expression: %v", err)
+	}
+
+	e.assignments = nil
+	for _, src := range e.Expressions {
+		a, err := parseAssignment(src)
+		if err != nil {
+			return fmt.Errorf("expression: %q: %v", src, err)
+		}
+		e.assignments = append(e.assignments, a)
+	}
+	return nil
+}
+
+func (e *Expression) Apply(in ...internal.Metric) []internal.Metric {
+	for _, m := range in {
+		if e.filter != nil && !e.filter.Match(m.Name()) {
+			continue
+		}
+		for _, a := range e.assignments {
+			v, err := a.expr.eval(m)
+			if err != nil {
+				log.Debugf("[processors.expression] %s: %s not set: %v", m.Name(), a.field, err)
+				continue
+			}
+			m.AddField(a.field, v.value())
+		}
+	}
+	return in
+}
+
+func init() {
+	processors.Add("expression", func() plugins.Processor {
+		return &Expression{}
+	})
+}
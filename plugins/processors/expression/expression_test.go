@@ -0,0 +1,96 @@
+package expression
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(name string, fields map[string]interface{}) internal.Metric {
+	return testutil.MustMetric(name, map[string]string{}, fields, time.Unix(0, 0))
+}
+
+func apply(t *testing.T, e *Expression, m internal.Metric) map[string]interface{} {
+	require.NoError(t, e.Init())
+	out := e.Apply(m)
+	require.Len(t, out, 1)
+	return out[0].Fields()
+}
+
+func TestExpressions(t *testing.T) {
+	e := &Expression{Expressions: []string{
+		"used_ratio = used / total",
+		"used_percent = used_ratio * 100",
+		"free = total - used",
+		"rss_mb = rss / 1048576",
+		"half = -(total % 3 - 4) * 0.5",
+	}}
+
+	fields := apply(t, e, newMetric("mem", map[string]interface{}{
+		"used":  uint64(256),
+		"total": uint64(1024),
+		"rss":   int64(3 * 1048576),
+	}))
+	require.Equal(t, map[string]interface{}{
+		"used":         uint64(256),
+		"total":        uint64(1024),
+		"rss":          int64(3 * 1048576),
+		"used_ratio":   0.25,
+		"used_percent": 25.0,
+		"free":         int64(768),
+		"rss_mb":       3.0,
+		"half":         1.5,
+	}, fields)
+}
+
+func TestMissingAndInvalid(t *testing.T) {
+	e := &Expression{Expressions: []string{
+		"ratio = used / total",
+		"missing = used + nope",
+		"text = used + name",
+		"overflow = used * 9223372036854775807",
+	}}
+
+	fields := apply(t, e, newMetric("mem", map[string]interface{}{
+		"used":  int64(2),
+		"total": int64(0),
+		"name":  "x",
+	}))
+	require.Equal(t, map[string]interface{}{
+		"used":  int64(2),
+		"total": int64(0),
+		"name":  "x",
+	}, fields)
+}
+
+func TestMeasurements(t *testing.T) {
+	e := &Expression{
+		Measurements: []string{"mem"},
+		Expressions:  []string{"double = value * 2"},
+	}
+
+	fields := apply(t, e, newMetric("cpu", map[string]interface{}{"value": 1.5}))
+	require.Equal(t, map[string]interface{}{"value": 1.5}, fields)
+
+	fields = apply(t, e, newMetric("mem", map[string]interface{}{"value": 1.5}))
+	require.Equal(t, map[string]interface{}{"value": 1.5, "double": 3.0}, fields)
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"used / total",
+		"= used",
+		"ratio = used /",
+		"ratio = (used",
+		"ratio = used total",
+		"ratio = used ^ 2",
+		"ratio = 1.2.3",
+	} {
+		e := &Expression{Expressions: []string{src}}
+		require.Error(t, e.Init(), src)
+	}
+	require.Error(t, (&Expression{}).Init())
+}
@@ -0,0 +1,279 @@
+package lookup
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/processors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var sampleConfig = `
+  ## Files with the lookup table.  Entries of later files replace those of
+  ## earlier ones with the same key.
+  files = ["/etc/straw/inventory.csv"]
+
+  ## Format of the files, "csv" or "json".  By default it is taken from the
+  ## file extension.
+  ##
+  ## A csv file has a header row; the first column holds the key and each
+  ## other column a tag to add:
+  ##   host,team,datacenter,rack
+  ##   web01,frontend,fra1,r12
+  ##
+  ## A json file maps each key to the tags to add:
+  ##   {"web01": {"team": "frontend", "datacenter": "fra1", "rack": "r12"}}
+  # format = ""
+
+  ## Tag whose value is looked up in the table.
+  key = "host"
+
+  ## Replace tags the metric already has.
+  # overwrite = false
+
+  ## How often to check the files for changes.  The files are checked in the
+  ## background; changed files are reloaded, the previous table is kept if a
+  ## file cannot be loaded.
+  # check_interval = "10s"
+`
+
+// Lookup adds tags from a table loaded from files to the metrics whose key
+// tag is in the table.
+type Lookup struct {
+	Files         []string          `toml:"files"`
+	Format        string            `toml:"format"`
+	Key           string            `toml:"key"`
+	Overwrite     bool              `toml:"overwrite"`
+	CheckInterval internal.Duration `toml:"check_interval"`
+
+	mu       sync.RWMutex
+	table    map[string][]internal.Tag
+	modTimes map[string]time.Time
+
+	ticker *time.Ticker
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func (*Lookup) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Lookup) Description() string {
+	return "Add tags from a csv or json lookup table"
+}
+
+func (l *Lookup) Init() error {
+	if len(l.Files) == 0 {
+		return fmt.Errorf("lookup: no files")
+	}
+	if l.Key == "" {
+		return fmt.Errorf("lookup: missing key")
+	}
+	for _, file := range l.Files {
+		if _, err := l.format(file); err != nil {
+			return err
+		}
+	}
+	if l.CheckInterval.Duration == 0 {
+		l.CheckInterval.Duration = 10 * time.Second
+	}
+
+	if err := l.load(); err != nil {
+		return err
+	}
+
+	l.ticker = time.NewTicker(l.CheckInterval.Duration)
+	l.done = make(chan struct{})
+	l.wg.Add(1)
+	go l.watch(l.ticker.C, l.done)
+	return nil
+}
+
+// Stop stops checking the files for changes.
+func (l *Lookup) Stop() {
+	if l.ticker == nil {
+		return
+	}
+	l.ticker.Stop()
+	close(l.done)
+	l.wg.Wait()
+	l.ticker = nil
+}
+
+func (l *Lookup) Apply(in ...internal.Metric) []internal.Metric {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, m := range in {
+		value, ok := m.GetTag(l.Key)
+		if !ok {
+			continue
+		}
+		for _, tag := range l.table[value] {
+			if l.Overwrite || !m.HasTag(tag.Key) {
+				m.AddTag(tag.Key, tag.Value)
+			}
+		}
+	}
+	return in
+}
+
+// watch checks the files for changes on every tick until done is closed, so
+// that Apply never waits for the file system.
+func (l *Lookup) watch(tick <-chan time.Time, done <-chan struct{}) {
+	defer l.wg.Done()
+	for {
+		select {
+		case <-tick:
+			l.reload()
+		case <-done:
+			return
+		}
+	}
+}
+
+// reload loads the files again if any of them has changed.
+func (l *Lookup) reload() {
+	l.mu.RLock()
+	modTimes := l.modTimes
+	l.mu.RUnlock()
+
+	changed := false
+	for _, file := range l.Files {
+		info, err := os.Stat(file)
+		if err != nil {
+			log.Errorf("[processors.lookup] Could not check %s: %v", file, err)
+			return
+		}
+		if !info.ModTime().Equal(modTimes[file]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	if err := l.load(); err != nil {
+		log.Errorf("[processors.lookup] Keeping the previous table: %v", err)
+		return
+	}
+	log.Infof("[processors.lookup] Reloaded the lookup table")
+}
+
+// load replaces the table with the contents of the files.
+func (l *Lookup) load() error {
+	table := make(map[string][]internal.Tag)
+	modTimes := make(map[string]time.Time, len(l.Files))
+	for _, file := range l.Files {
+		modTime, err := l.loadFile(file, table)
+		if err != nil {
+			return fmt.Errorf("lookup: %s: %v", file, err)
+		}
+		modTimes[file] = modTime
+	}
+
+	l.mu.Lock()
+	l.table = table
+	l.modTimes = modTimes
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *Lookup) loadFile(file string, table map[string][]internal.Tag) (time.Time, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	format, _ := l.format(file)
+	switch format {
+	case "csv":
+		err = loadCSV(f, table)
+	default:
+		err = loadJSON(f, table)
+	}
+	return info.ModTime(), err
+}
+
+// format returns the format of the file.
+func (l *Lookup) format(file string) (string, error) {
+	format := l.Format
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), ".")
+	}
+	switch format {
+	case "csv", "json":
+		return format, nil
+	}
+	return "", fmt.Errorf("lookup: unknown format of %s, set format to csv or json", file)
+}
+
+func loadCSV(r io.Reader, table map[string][]internal.Tag) error {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("reading header: %v", err)
+	}
+	if len(header) < 2 {
+		return fmt.Errorf("header needs a key column and at least one tag column")
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		tags := make([]internal.Tag, 0, len(record)-1)
+		for i, value := range record[1:] {
+			if value != "" {
+				tags = append(tags, internal.Tag{Key: header[i+1], Value: value})
+			}
+		}
+		table[record[0]] = tags
+	}
+}
+
+func loadJSON(r io.Reader, table map[string][]internal.Tag) error {
+	var entries map[string]map[string]string
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return err
+	}
+
+	for key, entry := range entries {
+		tags := make([]internal.Tag, 0, len(entry))
+		for k, v := range entry {
+			tags = append(tags, internal.Tag{Key: k, Value: v})
+		}
+		table[key] = tags
+	}
+	return nil
+}
+
+func init() {
+	processors.Add("lookup", func() plugins.Processor {
+		return &Lookup{}
+	})
+}
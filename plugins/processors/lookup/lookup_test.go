@@ -0,0 +1,122 @@
+package lookup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, contents string, modTime time.Time) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func newMetric(tags map[string]string) internal.Metric {
+	return testutil.MustMetric("cpu", tags,
+		map[string]interface{}{"usage_idle": 99.0}, time.Unix(0, 0))
+}
+
+func TestLookupCSVAndJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	csvFile := filepath.Join(dir, "inventory.csv")
+	writeFile(t, csvFile, "# inventory\nhost,team,datacenter,rack\nweb01,frontend,fra1,r12\ndb01,storage,fra1,\n", time.Unix(100, 0))
+	jsonFile := filepath.Join(dir, "overrides.json")
+	writeFile(t, jsonFile, `{"db01": {"team": "dba"}}`, time.Unix(100, 0))
+
+	l := &Lookup{Files: []string{csvFile, jsonFile}, Key: "host"}
+	require.NoError(t, l.Init())
+	defer l.Stop()
+
+	out := l.Apply(
+		newMetric(map[string]string{"host": "web01", "team": "mine"}),
+		newMetric(map[string]string{"host": "db01"}),
+		newMetric(map[string]string{"host": "unknown"}),
+		newMetric(nil),
+	)
+	require.Len(t, out, 4)
+	require.Equal(t, map[string]string{
+		"host": "web01", "team": "mine", "datacenter": "fra1", "rack": "r12",
+	}, out[0].Tags())
+	require.Equal(t, map[string]string{"host": "db01", "team": "dba"}, out[1].Tags())
+	require.Equal(t, map[string]string{"host": "unknown"}, out[2].Tags())
+	require.Empty(t, out[3].Tags())
+
+	l.Overwrite = true
+	out = l.Apply(newMetric(map[string]string{"host": "web01", "team": "mine"}))
+	require.Equal(t, "frontend", out[0].Tags()["team"])
+}
+
+func TestLookupReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "inventory.json")
+	writeFile(t, file, `{"web01": {"rack": "r1"}}`, time.Unix(100, 0))
+
+	l := &Lookup{Files: []string{file}, Key: "host"}
+	require.NoError(t, l.Init())
+	defer l.Stop()
+
+	// A broken file keeps the previous table.
+	writeFile(t, file, `{"web01": `, time.Unix(200, 0))
+	l.reload()
+	out := l.Apply(newMetric(map[string]string{"host": "web01"}))
+	require.Equal(t, "r1", out[0].Tags()["rack"])
+
+	// An unchanged file is not loaded again.
+	writeFile(t, file, `{"web01": {"rack": "r2"}}`, time.Unix(100, 0))
+	l.reload()
+	out = l.Apply(newMetric(map[string]string{"host": "web01"}))
+	require.Equal(t, "r1", out[0].Tags()["rack"])
+
+	writeFile(t, file, `{"web01": {"rack": "r2"}}`, time.Unix(300, 0))
+	l.reload()
+	out = l.Apply(newMetric(map[string]string{"host": "web01"}))
+	require.Equal(t, "r2", out[0].Tags()["rack"])
+}
+
+func TestLookupWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "inventory.json")
+	writeFile(t, file, `{"web01": {"rack": "r1"}}`, time.Unix(100, 0))
+
+	l := &Lookup{
+		Files:         []string{file},
+		Key:           "host",
+		CheckInterval: internal.Duration{Duration: time.Millisecond},
+	}
+	require.NoError(t, l.Init())
+
+	writeFile(t, file, `{"web01": {"rack": "r2"}}`, time.Unix(200, 0))
+	require.Eventually(t, func() bool {
+		out := l.Apply(newMetric(map[string]string{"host": "web01"}))
+		return out[0].Tags()["rack"] == "r2"
+	}, time.Second, time.Millisecond)
+
+	// Stop waits for the watch to return and may be called again.
+	l.Stop()
+	l.Stop()
+	writeFile(t, file, `{"web01": {"rack": "r3"}}`, time.Unix(300, 0))
+	time.Sleep(10 * time.Millisecond)
+	out := l.Apply(newMetric(map[string]string{"host": "web01"}))
+	require.Equal(t, "r2", out[0].Tags()["rack"])
+}
+
+func TestLookupInit(t *testing.T) {
+	require.Error(t, (&Lookup{Key: "host"}).Init())
+	require.Error(t, (&Lookup{Files: []string{"table.txt"}, Key: "host"}).Init())
+	require.Error(t, (&Lookup{Files: []string{"/nonexistent.csv"}, Key: "host"}).Init())
+}
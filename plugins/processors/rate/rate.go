@@ -0,0 +1,269 @@
+package rate
+
+import (
+	"fmt"
+	"github.com/geekflow/straw/filter"
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/processors"
+	"math"
+	"strings"
+	"time"
+)
+
+var sampleConfig = `
+  ## Fields to compute the rate of, glob patterns are supported.  Only
+  ## numeric fields are used.
+  fields = ["*"]
+
+  ## What to report for each field, one of:
+  ##   "rate"    - the change per second
+  ##   "delta"   - the change since the previous sample
+  ##   "percent" - the change as a percentage of the change of total_field
+  # mode = "rate"
+  # total_field = ""
+
+  ## Name of the result fields, made by removing trim_prefix from the field
+  ## key and adding prefix and suffix.
+  # trim_prefix = ""
+  # prefix = ""
+  # suffix = ""
+
+  ## Keep the original fields next to the results.
+  # keep_original = false
+
+  ## Forget series without a sample for this long.  Must be longer than the
+  ## collection interval.
+  # max_age = "1h"
+`
+
+type mode int
+
+const (
+	modeRate mode = iota
+	modeDelta
+	modePercent
+)
+
+// Rate replaces monotonic counters with their rate of change per series.
+// The first sample of a series only primes the state.
+type Rate struct {
+	Fields       []string          `toml:"fields"`
+	Mode         string            `toml:"mode"`
+	TotalField   string            `toml:"total_field"`
+	TrimPrefix   string            `toml:"trim_prefix"`
+	Prefix       string            `toml:"prefix"`
+	Suffix       string            `toml:"suffix"`
+	KeepOriginal bool              `toml:"keep_original"`
+	MaxAge       internal.Duration `toml:"max_age"`
+
+	mode      mode
+	filter    filter.Filter
+	cache     map[uint64]*sample
+	lastPurge time.Time
+}
+
+// sample is the previous state of a series.
+type sample struct {
+	time   time.Time
+	values map[string]interface{}
+}
+
+func (*Rate) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Rate) Description() string {
+	return "Compute the rate of change of counter fields"
+}
+
+func (r *Rate) Init() error {
+	switch r.Mode {
+	case "", "rate":
+		r.mode = modeRate
+	case "delta":
+		r.mode = modeDelta
+	case "percent":
+		r.mode = modePercent
+		if r.TotalField == "" {
+			return fmt.Errorf("rate: total_field is required in percent mode")
+		}
+	default:
+		return fmt.Errorf("rate: invalid mode %q", r.Mode)
+	}
+
+	fields := r.Fields
+	if len(fields) == 0 {
+		fields = []string{"*"}
+	}
+
+	var err error
+	r.filter, err = filter.Compile(fields)
+	if err != nil {
+		return fmt.Errorf("rate: %v", err)
+	}
+
+	r.cache = make(map[uint64]*sample)
+	return nil
+}
+
+func (r *Rate) Apply(in ...internal.Metric) []internal.Metric {
+	out := in[:0]
+	for _, m := range in {
+		r.apply(m)
+		if len(m.FieldList()) == 0 {
+			m.Drop()
+			continue
+		}
+		out = append(out, m)
+	}
+
+	if len(in) > 0 {
+		r.purge(in[len(in)-1].Time())
+	}
+	return out
+}
+
+type result struct {
+	key   string
+	value float64
+}
+
+func (r *Rate) apply(m internal.Metric) {
+	id := m.HashID()
+	prev := r.cache[id]
+	cur := &sample{time: m.Time(), values: make(map[string]interface{})}
+
+	var totalDelta float64
+	totalOK := false
+	if r.mode == modePercent {
+		if v, ok := m.GetField(r.TotalField); ok && isNumeric(v) {
+			cur.values[r.TotalField] = v
+			if prev != nil {
+				totalDelta, totalOK = counterDelta(prev.values[r.TotalField], v)
+			}
+		}
+	}
+
+	var elapsed float64
+	if prev != nil {
+		elapsed = cur.time.Sub(prev.time).Seconds()
+	}
+
+	var matched []string
+	var results []result
+	for _, field := range m.FieldList() {
+		if !r.filter.Match(field.Key) || !isNumeric(field.Value) {
+			continue
+		}
+		matched = append(matched, field.Key)
+		if field.Key == r.TotalField && r.mode == modePercent {
+			continue
+		}
+		cur.values[field.Key] = field.Value
+
+		if prev == nil {
+			continue
+		}
+		delta, ok := counterDelta(prev.values[field.Key], field.Value)
+		if !ok {
+			continue
+		}
+
+		switch r.mode {
+		case modeDelta:
+		case modeRate:
+			if elapsed <= 0 {
+				continue
+			}
+			delta = delta / elapsed
+		case modePercent:
+			if !totalOK || totalDelta <= 0 {
+				continue
+			}
+			delta = 100 * delta / totalDelta
+		}
+		results = append(results, result{key: r.resultKey(field.Key), value: delta})
+	}
+
+	r.cache[id] = cur
+
+	if !r.KeepOriginal {
+		for _, key := range matched {
+			m.RemoveField(key)
+		}
+	}
+	for _, res := range results {
+		m.AddField(res.key, res.value)
+	}
+}
+
+func (r *Rate) resultKey(key string) string {
+	if key != r.TrimPrefix {
+		key = strings.TrimPrefix(key, r.TrimPrefix)
+	}
+	return r.Prefix + key + r.Suffix
+}
+
+// purge forgets the series without a sample within max_age of now.
+func (r *Rate) purge(now time.Time) {
+	maxAge := r.MaxAge.Duration
+	if maxAge <= 0 {
+		maxAge = time.Hour
+	}
+	if now.Sub(r.lastPurge) < maxAge {
+		return
+	}
+
+	for id, s := range r.cache {
+		if now.Sub(s.time) > maxAge {
+			delete(r.cache, id)
+		}
+	}
+	r.lastPurge = now
+}
+
+func isNumeric(v interface{}) bool {
+	switch v.(type) {
+	case int64, uint64, float64:
+		return true
+	}
+	return false
+}
+
+// counterDelta returns the increase of a counter between two samples of the
+// same type.  An unsigned counter that decreases from the upper half of its
+// range has wrapped around; any other decrease is a counter reset, for which
+// no delta is returned.
+func counterDelta(prev, cur interface{}) (float64, bool) {
+	switch c := cur.(type) {
+	case uint64:
+		p, ok := prev.(uint64)
+		if !ok {
+			return 0, false
+		}
+		if c >= p {
+			return float64(c - p), true
+		}
+		if p >= 1<<63 {
+			return float64(c + (math.MaxUint64 - p) + 1), true
+		}
+	case int64:
+		p, ok := prev.(int64)
+		if ok && c >= p {
+			return float64(c - p), true
+		}
+	case float64:
+		p, ok := prev.(float64)
+		if ok && c >= p {
+			return c - p, true
+		}
+	}
+	return 0, false
+}
+
+func init() {
+	processors.Add("rate", func() plugins.Processor {
+		return &Rate{}
+	})
+}
@@ -0,0 +1,132 @@
+package rate
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func counter(host string, fields map[string]interface{}, sec int64) internal.Metric {
+	return testutil.MustMetric("net",
+		map[string]string{"interface": host},
+		fields,
+		time.Unix(sec, 0))
+}
+
+func TestRate(t *testing.T) {
+	r := &Rate{Fields: []string{"bytes_*"}, Suffix: "_per_sec"}
+	require.NoError(t, r.Init())
+
+	// The first sample only primes the state.
+	out := r.Apply(counter("eth0", map[string]interface{}{
+		"bytes_recv": uint64(1000),
+		"bytes_sent": int64(500),
+		"drop_in":    uint64(1),
+	}, 0))
+	require.Len(t, out, 1)
+	require.Equal(t, map[string]interface{}{"drop_in": uint64(1)}, out[0].Fields())
+
+	out = r.Apply(counter("eth0", map[string]interface{}{
+		"bytes_recv": uint64(3000),
+		"bytes_sent": int64(1500),
+		"drop_in":    uint64(2),
+	}, 10))
+	require.Len(t, out, 1)
+	require.Equal(t, map[string]interface{}{
+		"bytes_recv_per_sec": 200.0,
+		"bytes_sent_per_sec": 100.0,
+		"drop_in":            uint64(2),
+	}, out[0].Fields())
+
+	// Series are independent.
+	out = r.Apply(counter("eth1", map[string]interface{}{"bytes_recv": uint64(1)}, 10))
+	require.Len(t, out, 0)
+}
+
+func TestDeltaKeepOriginal(t *testing.T) {
+	r := &Rate{Mode: "delta", Prefix: "delta_", KeepOriginal: true}
+	require.NoError(t, r.Init())
+
+	r.Apply(counter("eth0", map[string]interface{}{"packets": int64(10)}, 0))
+	out := r.Apply(counter("eth0", map[string]interface{}{"packets": int64(25)}, 10))
+	require.Len(t, out, 1)
+	require.Equal(t, map[string]interface{}{
+		"packets":       int64(25),
+		"delta_packets": 15.0,
+	}, out[0].Fields())
+}
+
+func TestResetAndWraparound(t *testing.T) {
+	r := &Rate{Mode: "delta"}
+	require.NoError(t, r.Init())
+
+	r.Apply(counter("eth0", map[string]interface{}{
+		"wrap":  uint64(math.MaxUint64 - 9),
+		"reset": uint64(1000),
+		"float": 5.0,
+	}, 0))
+
+	out := r.Apply(counter("eth0", map[string]interface{}{
+		"wrap":  uint64(5),
+		"reset": uint64(10),
+		"float": 1.0,
+	}, 10))
+	require.Len(t, out, 1)
+	require.Equal(t, map[string]interface{}{"wrap": 15.0}, out[0].Fields())
+
+	// The reset sample becomes the new base.
+	out = r.Apply(counter("eth0", map[string]interface{}{
+		"wrap":  uint64(6),
+		"reset": uint64(15),
+		"float": 3.0,
+	}, 20))
+	require.Len(t, out, 1)
+	require.Equal(t, map[string]interface{}{
+		"wrap":  1.0,
+		"reset": 5.0,
+		"float": 2.0,
+	}, out[0].Fields())
+}
+
+func TestPercent(t *testing.T) {
+	r := &Rate{
+		Fields:     []string{"time_*"},
+		Mode:       "percent",
+		TotalField: "time_total",
+		TrimPrefix: "time_",
+		Prefix:     "usage_",
+	}
+	require.NoError(t, r.Init())
+
+	r.Apply(counter("cpu0", map[string]interface{}{
+		"time_user": 10.0, "time_idle": 90.0, "time_total": 100.0,
+	}, 0))
+	out := r.Apply(counter("cpu0", map[string]interface{}{
+		"time_user": 30.0, "time_idle": 170.0, "time_total": 200.0,
+	}, 10))
+	require.Len(t, out, 1)
+	require.Equal(t, map[string]interface{}{
+		"usage_user": 20.0,
+		"usage_idle": 80.0,
+	}, out[0].Fields())
+
+	r = &Rate{Mode: "percent"}
+	require.Error(t, r.Init())
+}
+
+func TestMaxAge(t *testing.T) {
+	r := &Rate{MaxAge: internal.Duration{Duration: time.Minute}}
+	require.NoError(t, r.Init())
+
+	r.Apply(counter("eth0", map[string]interface{}{"bytes": int64(0)}, 0))
+	r.Apply(counter("eth1", map[string]interface{}{"bytes": int64(0)}, 120))
+	require.Len(t, r.cache, 1)
+
+	// eth0 was forgotten, so this is a new first sample.
+	out := r.Apply(counter("eth0", map[string]interface{}{"bytes": int64(10)}, 130))
+	require.Len(t, out, 0)
+}
@@ -0,0 +1,235 @@
+package regex
+
+import (
+	"fmt"
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/processors"
+	"regexp"
+)
+
+// Regex rewrites measurement names, tag keys and values, and field keys and
+// string values with regular expressions.
+type Regex struct {
+	Tags         []converter `toml:"tags"`
+	Fields       []converter `toml:"fields"`
+	TagRename    []renamer   `toml:"tag_rename"`
+	FieldRename  []renamer   `toml:"field_rename"`
+	MetricRename []renamer   `toml:"metric_rename"`
+}
+
+// converter rewrites the value of a tag or string field.
+type converter struct {
+	Key         string `toml:"key"`
+	Pattern     string `toml:"pattern"`
+	Replacement string `toml:"replacement"`
+	// ResultKey, if set, writes the result to a new tag or field and leaves
+	// the original unchanged.
+	ResultKey string `toml:"result_key"`
+
+	re *regexp.Regexp
+}
+
+// renamer rewrites measurement names, or the keys of tags or fields.
+type renamer struct {
+	Pattern     string `toml:"pattern"`
+	Replacement string `toml:"replacement"`
+	// Overwrite allows a renamed tag or field to replace an existing one
+	// with the new key, otherwise the rename is skipped.
+	Overwrite bool `toml:"overwrite"`
+
+	re *regexp.Regexp
+}
+
+var sampleConfig = `
+  ## Rules are applied in the order they are defined within each section.
+  ## Sections are applied in the order: tags, fields, tag_rename,
+  ## field_rename, metric_rename.  To apply rules in a different order,
+  ## declare them in separate regex processors; processors run in the order
+  ## they are declared.
+
+  ## Rewrite the value of a tag.  Capture groups of the pattern can be used
+  ## in the replacement as ${1}.  The value is only changed if the pattern
+  ## matches.
+  [[inputs.procstat.processors.regex.tags]]
+    key = "process_name"
+    pattern = "^(nginx|httpd)$"
+    replacement = "web"
+    ## Write the result to a new tag instead of in place.
+    # result_key = "process_group"
+
+  ## Rewrite the value of a string field.
+  # [[inputs.procstat.processors.regex.fields]]
+  #   key = "cmdline"
+  #   pattern = "^/usr/bin/(\\w+).*"
+  #   replacement = "${1}"
+  #   result_key = "command"
+
+  ## Rename tag keys or field keys matching the pattern.  A key is not
+  ## renamed if the new key already exists, unless overwrite is set.
+  # [[inputs.procstat.processors.regex.tag_rename]]
+  #   pattern = "^process_name$"
+  #   replacement = "exe"
+  #   overwrite = false
+
+  # [[inputs.procstat.processors.regex.field_rename]]
+  #   pattern = "^memory_(.*)$"
+  #   replacement = "mem_${1}"
+
+  ## Rename measurements matching the pattern.
+  # [[inputs.procstat.processors.regex.metric_rename]]
+  #   pattern = "^procstat$"
+  #   replacement = "process"
+`
+
+func (*Regex) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Regex) Description() string {
+	return "Transforms names, tags and fields with regex patterns"
+}
+
+func (r *Regex) Init() error {
+	for i := range r.Tags {
+		if err := r.Tags[i].compile(); err != nil {
+			return err
+		}
+	}
+	for i := range r.Fields {
+		if err := r.Fields[i].compile(); err != nil {
+			return err
+		}
+	}
+	for _, renamers := range [][]renamer{r.TagRename, r.FieldRename, r.MetricRename} {
+		for i := range renamers {
+			if err := renamers[i].compile(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *converter) compile() error {
+	if c.Key == "" {
+		return fmt.Errorf("regex: missing key for pattern %q", c.Pattern)
+	}
+
+	re, err := regexp.Compile(c.Pattern)
+	if err != nil {
+		return fmt.Errorf("regex: invalid pattern for key %q: %v", c.Key, err)
+	}
+	c.re = re
+	return nil
+}
+
+func (rn *renamer) compile() error {
+	re, err := regexp.Compile(rn.Pattern)
+	if err != nil {
+		return fmt.Errorf("regex: invalid rename pattern: %v", err)
+	}
+	rn.re = re
+	return nil
+}
+
+func (r *Regex) Apply(in ...internal.Metric) []internal.Metric {
+	for _, m := range in {
+		for _, c := range r.Tags {
+			if value, ok := m.GetTag(c.Key); ok {
+				if result, ok := c.convert(value); ok {
+					m.AddTag(c.resultKey(), result)
+				}
+			}
+		}
+
+		for _, c := range r.Fields {
+			if value, ok := m.GetField(c.Key); ok {
+				if s, ok := value.(string); ok {
+					if result, ok := c.convert(s); ok {
+						m.AddField(c.resultKey(), result)
+					}
+				}
+			}
+		}
+
+		for _, rn := range r.TagRename {
+			for _, tag := range tagKeys(m) {
+				key, ok := rn.rename(tag)
+				if !ok || (m.HasTag(key) && !rn.Overwrite) {
+					continue
+				}
+				value, _ := m.GetTag(tag)
+				m.RemoveTag(tag)
+				m.AddTag(key, value)
+			}
+		}
+
+		for _, rn := range r.FieldRename {
+			for _, field := range fieldKeys(m) {
+				key, ok := rn.rename(field)
+				if !ok || (m.HasField(key) && !rn.Overwrite) {
+					continue
+				}
+				value, _ := m.GetField(field)
+				m.RemoveField(field)
+				m.AddField(key, value)
+			}
+		}
+
+		for _, rn := range r.MetricRename {
+			if name, ok := rn.rename(m.Name()); ok {
+				m.SetName(name)
+			}
+		}
+	}
+	return in
+}
+
+// convert returns the replaced value if the pattern matches.
+func (c *converter) convert(value string) (string, bool) {
+	if !c.re.MatchString(value) {
+		return "", false
+	}
+	return c.re.ReplaceAllString(value, c.Replacement), true
+}
+
+func (c *converter) resultKey() string {
+	if c.ResultKey != "" {
+		return c.ResultKey
+	}
+	return c.Key
+}
+
+// rename returns the new key if the pattern matches and changes the key.
+func (rn *renamer) rename(key string) (string, bool) {
+	if !rn.re.MatchString(key) {
+		return "", false
+	}
+	result := rn.re.ReplaceAllString(key, rn.Replacement)
+	return result, result != "" && result != key
+}
+
+// tagKeys returns the tag keys of the metric, so that tags can be renamed
+// while iterating.
+func tagKeys(m internal.Metric) []string {
+	keys := make([]string, 0, len(m.TagList()))
+	for _, tag := range m.TagList() {
+		keys = append(keys, tag.Key)
+	}
+	return keys
+}
+
+func fieldKeys(m internal.Metric) []string {
+	keys := make([]string, 0, len(m.FieldList()))
+	for _, field := range m.FieldList() {
+		keys = append(keys, field.Key)
+	}
+	return keys
+}
+
+func init() {
+	processors.Add("regex", func() plugins.Processor {
+		return &Regex{}
+	})
+}
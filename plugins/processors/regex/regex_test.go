@@ -0,0 +1,109 @@
+package regex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric() internal.Metric {
+	return testutil.MustMetric("procstat",
+		map[string]string{"process_name": "nginx", "host": "web01"},
+		map[string]interface{}{
+			"cmdline":    "/usr/sbin/nginx -g daemon off;",
+			"memory_rss": int64(1024),
+			"memory_vms": int64(4096),
+		},
+		time.Unix(0, 0))
+}
+
+func apply(t *testing.T, r *Regex, m internal.Metric) internal.Metric {
+	require.NoError(t, r.Init())
+	out := r.Apply(m)
+	require.Len(t, out, 1)
+	return out[0]
+}
+
+func TestTagValues(t *testing.T) {
+	r := &Regex{Tags: []converter{
+		{Key: "process_name", Pattern: "^(nginx|httpd)$", Replacement: "web-${1}"},
+		{Key: "host", Pattern: "^db", Replacement: "database"},
+		{Key: "missing", Pattern: ".*", Replacement: "x"},
+	}}
+
+	m := apply(t, r, newMetric())
+	require.Equal(t, map[string]string{
+		"process_name": "web-nginx",
+		"host":         "web01",
+	}, m.Tags())
+}
+
+func TestResultKey(t *testing.T) {
+	r := &Regex{
+		Tags: []converter{
+			{Key: "process_name", Pattern: "^nginx$", Replacement: "web", ResultKey: "group"},
+		},
+		Fields: []converter{
+			{Key: "cmdline", Pattern: `^/usr/sbin/(\w+).*`, Replacement: "${1}", ResultKey: "command"},
+			{Key: "memory_rss", Pattern: ".*", Replacement: "x"},
+		},
+	}
+
+	m := apply(t, r, newMetric())
+	require.Equal(t, "nginx", m.Tags()["process_name"])
+	require.Equal(t, "web", m.Tags()["group"])
+	require.Equal(t, "/usr/sbin/nginx -g daemon off;", m.Fields()["cmdline"])
+	require.Equal(t, "nginx", m.Fields()["command"])
+	// Only string fields are rewritten.
+	require.Equal(t, int64(1024), m.Fields()["memory_rss"])
+}
+
+func TestRename(t *testing.T) {
+	r := &Regex{
+		TagRename: []renamer{
+			{Pattern: "^process_name$", Replacement: "exe"},
+			{Pattern: "^exe$", Replacement: "host"},
+		},
+		FieldRename: []renamer{
+			{Pattern: "^memory_(.*)$", Replacement: "mem_${1}"},
+		},
+		MetricRename: []renamer{
+			{Pattern: "^procstat$", Replacement: "process"},
+		},
+	}
+
+	m := apply(t, r, newMetric())
+	require.Equal(t, "process", m.Name())
+	// The second rename is skipped since host exists.
+	require.Equal(t, map[string]string{"exe": "nginx", "host": "web01"}, m.Tags())
+	require.Equal(t, map[string]interface{}{
+		"cmdline": "/usr/sbin/nginx -g daemon off;",
+		"mem_rss": int64(1024),
+		"mem_vms": int64(4096),
+	}, m.Fields())
+
+	r.TagRename[1].Overwrite = true
+	m = apply(t, r, newMetric())
+	require.Equal(t, map[string]string{"host": "nginx"}, m.Tags())
+}
+
+func TestRenameDoesNotChangeCopies(t *testing.T) {
+	r := &Regex{TagRename: []renamer{{Pattern: "^host$", Replacement: "hostname"}}}
+
+	m := newMetric()
+	orig := m.Copy()
+	apply(t, r, m)
+	require.Equal(t, "web01", orig.Tags()["host"])
+	require.False(t, orig.HasTag("hostname"))
+}
+
+func TestInvalidPattern(t *testing.T) {
+	r := &Regex{Tags: []converter{{Key: "host", Pattern: "("}}}
+	require.Error(t, r.Init())
+
+	r = &Regex{Tags: []converter{{Pattern: ".*"}}}
+	require.Error(t, r.Init())
+}
@@ -0,0 +1,11 @@
+package processors
+
+import "github.com/geekflow/straw/plugins"
+
+type Creator func() plugins.Processor
+
+var Processors = map[string]Creator{}
+
+func Add(name string, creator Creator) {
+	Processors[name] = creator
+}
@@ -0,0 +1,476 @@
+package script
+
+import (
+	"fmt"
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/metric"
+	"sort"
+	"strings"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+// Metric is a metric passed to or created by a script.
+type Metric struct {
+	metric internal.Metric
+}
+
+func (m *Metric) String() string {
+	return fmt.Sprintf("Metric(%q, tags=%s, fields=%s, time=%d)",
+		m.metric.Name(), m.view(false).String(), m.view(true).String(), m.metric.Time().UnixNano())
+}
+
+func (*Metric) Type() string          { return "Metric" }
+func (*Metric) Freeze()               {}
+func (*Metric) Truth() starlark.Bool  { return true }
+func (*Metric) Hash() (uint32, error) { return 0, fmt.Errorf("unhashable type: Metric") }
+func (*Metric) AttrNames() []string   { return []string{"fields", "name", "tags", "time"} }
+
+func (m *Metric) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "name":
+		return starlark.String(m.metric.Name()), nil
+	case "tags":
+		return m.view(false), nil
+	case "fields":
+		return m.view(true), nil
+	case "time":
+		return starlark.MakeInt64(m.metric.Time().UnixNano()), nil
+	}
+	return nil, nil
+}
+
+func (m *Metric) SetField(name string, v starlark.Value) error {
+	switch name {
+	case "name":
+		s, ok := v.(starlark.String)
+		if !ok {
+			return fmt.Errorf("Metric name must be a string, not %s", v.Type())
+		}
+		m.metric.SetName(string(s))
+		return nil
+	case "time":
+		ns, err := toInt64(v)
+		if err != nil {
+			return fmt.Errorf("Metric time %v", err)
+		}
+		m.metric.SetTime(time.Unix(0, ns))
+		return nil
+	case "tags", "fields":
+		items, err := stringItems(v)
+		if err != nil {
+			return err
+		}
+		dst := m.view(name == "fields")
+		for _, key := range dst.keys() {
+			dst.remove(key)
+		}
+		return dst.update(items)
+	}
+	return starlark.NoSuchAttrError(fmt.Sprintf("Metric has no attribute %s", name))
+}
+
+func (m *Metric) view(fields bool) *Mapping {
+	return &Mapping{metric: m.metric, fields: fields}
+}
+
+// Mapping is a dict-like view of the tags or the fields of a metric.  Keys
+// are strings.
+type Mapping struct {
+	metric internal.Metric
+	fields bool
+}
+
+var mappingMethods = map[string]func(m *Mapping, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error){
+	"clear":      mappingClear,
+	"get":        mappingGet,
+	"items":      mappingItems,
+	"keys":       mappingKeys,
+	"pop":        mappingPop,
+	"setdefault": mappingSetdefault,
+	"update":     mappingUpdate,
+	"values":     mappingValues,
+}
+
+func (m *Mapping) String() string {
+	keys := m.keys()
+	s := make([]string, len(keys))
+	for i, key := range keys {
+		v, _ := m.get(key)
+		s[i] = starlark.String(key).String() + ": " + v.String()
+	}
+	return "{" + strings.Join(s, ", ") + "}"
+}
+
+func (m *Mapping) Type() string {
+	if m.fields {
+		return "Fields"
+	}
+	return "Tags"
+}
+
+func (*Mapping) Freeze()                {}
+func (m *Mapping) Truth() starlark.Bool { return m.Len() > 0 }
+func (m *Mapping) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: %s", m.Type())
+}
+
+func (m *Mapping) Len() int {
+	if m.fields {
+		return len(m.metric.FieldList())
+	}
+	return len(m.metric.TagList())
+}
+
+func (m *Mapping) AttrNames() []string {
+	names := make([]string, 0, len(mappingMethods))
+	for name := range mappingMethods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m *Mapping) Attr(name string) (starlark.Value, error) {
+	method, ok := mappingMethods[name]
+	if !ok {
+		return nil, nil
+	}
+	return starlark.NewBuiltin(name, func(_ *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		return method(m, args, kwargs)
+	}).BindReceiver(m), nil
+}
+
+func (m *Mapping) Get(k starlark.Value) (starlark.Value, bool, error) {
+	key, ok := k.(starlark.String)
+	if !ok {
+		return nil, false, fmt.Errorf("%s keys must be strings, not %s", m.Type(), k.Type())
+	}
+	v, ok := m.get(string(key))
+	return v, ok, nil
+}
+
+func (m *Mapping) SetKey(k, v starlark.Value) error {
+	key, ok := k.(starlark.String)
+	if !ok {
+		return fmt.Errorf("%s keys must be strings, not %s", m.Type(), k.Type())
+	}
+	return m.set(string(key), v)
+}
+
+// Iterate iterates over the keys present when it is called, so that the
+// mapping may be modified in the loop.
+func (m *Mapping) Iterate() starlark.Iterator {
+	keys := m.keys()
+	values := make([]starlark.Value, len(keys))
+	for i, key := range keys {
+		values[i] = starlark.String(key)
+	}
+	return &iterator{values: values}
+}
+
+func (m *Mapping) Items() []starlark.Tuple {
+	keys := m.keys()
+	items := make([]starlark.Tuple, len(keys))
+	for i, key := range keys {
+		v, _ := m.get(key)
+		items[i] = starlark.Tuple{starlark.String(key), v}
+	}
+	return items
+}
+
+func (m *Mapping) keys() []string {
+	var keys []string
+	if m.fields {
+		for _, field := range m.metric.FieldList() {
+			keys = append(keys, field.Key)
+		}
+	} else {
+		for _, tag := range m.metric.TagList() {
+			keys = append(keys, tag.Key)
+		}
+	}
+	return keys
+}
+
+func (m *Mapping) get(key string) (starlark.Value, bool) {
+	if !m.fields {
+		v, ok := m.metric.GetTag(key)
+		return starlark.String(v), ok
+	}
+	v, ok := m.metric.GetField(key)
+	if !ok {
+		return nil, false
+	}
+	return fromField(v), true
+}
+
+func (m *Mapping) set(key string, v starlark.Value) error {
+	value, err := m.value(v)
+	if err != nil {
+		return err
+	}
+	if m.fields {
+		m.metric.AddField(key, value)
+	} else {
+		m.metric.AddTag(key, value.(string))
+	}
+	return nil
+}
+
+// value converts a value set by the script to a tag or field value.
+func (m *Mapping) value(v starlark.Value) (interface{}, error) {
+	if m.fields {
+		return toField(v)
+	}
+	s, ok := v.(starlark.String)
+	if !ok {
+		return nil, fmt.Errorf("tag value must be a string, not %s", v.Type())
+	}
+	return string(s), nil
+}
+
+func (m *Mapping) remove(key string) {
+	if m.fields {
+		m.metric.RemoveField(key)
+	} else {
+		m.metric.RemoveTag(key)
+	}
+}
+
+// update sets the items in the mapping, all values are checked first so
+// that a failed update leaves the mapping unchanged.
+func (m *Mapping) update(items []starlark.Tuple) error {
+	for _, item := range items {
+		if _, err := m.value(item[1]); err != nil {
+			return err
+		}
+	}
+	for _, item := range items {
+		m.set(string(item[0].(starlark.String)), item[1])
+	}
+	return nil
+}
+
+func mappingClear(m *Mapping, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs("clear", args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	for _, key := range m.keys() {
+		m.remove(key)
+	}
+	return starlark.None, nil
+}
+
+func mappingGet(m *Mapping, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key string
+	var dflt starlark.Value = starlark.None
+	if err := starlark.UnpackPositionalArgs("get", args, kwargs, 1, &key, &dflt); err != nil {
+		return nil, err
+	}
+	if v, ok := m.get(key); ok {
+		return v, nil
+	}
+	return dflt, nil
+}
+
+func mappingItems(m *Mapping, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs("items", args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	items := m.Items()
+	values := make([]starlark.Value, len(items))
+	for i, item := range items {
+		values[i] = item
+	}
+	return starlark.NewList(values), nil
+}
+
+func mappingKeys(m *Mapping, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs("keys", args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	var values []starlark.Value
+	for _, key := range m.keys() {
+		values = append(values, starlark.String(key))
+	}
+	return starlark.NewList(values), nil
+}
+
+func mappingPop(m *Mapping, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key string
+	var dflt starlark.Value
+	if err := starlark.UnpackPositionalArgs("pop", args, kwargs, 1, &key, &dflt); err != nil {
+		return nil, err
+	}
+	v, ok := m.get(key)
+	if !ok {
+		if dflt == nil {
+			return nil, fmt.Errorf("pop: missing key %q", key)
+		}
+		return dflt, nil
+	}
+	m.remove(key)
+	return v, nil
+}
+
+func mappingSetdefault(m *Mapping, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key string
+	var dflt starlark.Value = starlark.None
+	if err := starlark.UnpackPositionalArgs("setdefault", args, kwargs, 1, &key, &dflt); err != nil {
+		return nil, err
+	}
+	if v, ok := m.get(key); ok {
+		return v, nil
+	}
+	if err := m.set(key, dflt); err != nil {
+		return nil, err
+	}
+	return dflt, nil
+}
+
+func mappingUpdate(m *Mapping, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var src starlark.Value = starlark.NewDict(0)
+	if err := starlark.UnpackPositionalArgs("update", args, nil, 0, &src); err != nil {
+		return nil, err
+	}
+	items, err := stringItems(src)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.update(append(items, kwargs...)); err != nil {
+		return nil, err
+	}
+	return starlark.None, nil
+}
+
+func mappingValues(m *Mapping, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs("values", args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	var values []starlark.Value
+	for _, key := range m.keys() {
+		v, _ := m.get(key)
+		values = append(values, v)
+	}
+	return starlark.NewList(values), nil
+}
+
+type iterator struct {
+	values []starlark.Value
+}
+
+func (it *iterator) Next(p *starlark.Value) bool {
+	if len(it.values) == 0 {
+		return false
+	}
+	*p = it.values[0]
+	it.values = it.values[1:]
+	return true
+}
+
+func (it *iterator) Done() {}
+
+// makeMetric implements Metric(name, tags=None, fields=None, time=None).  The
+// time defaults to the time of the metric being processed.
+func makeMetric(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	var tags, fields, tm starlark.Value = starlark.None, starlark.None, starlark.None
+	if err := starlark.UnpackArgs("Metric", args, kwargs, "name", &name, "tags?", &tags, "fields?", &fields, "time?", &tm); err != nil {
+		return nil, err
+	}
+
+	now, _ := thread.Local("now").(time.Time)
+	mm, err := metric.New(name, nil, nil, now)
+	if err != nil {
+		return nil, err
+	}
+	m := &Metric{metric: mm}
+	for _, attr := range []struct {
+		name  string
+		value starlark.Value
+	}{{"tags", tags}, {"fields", fields}, {"time", tm}} {
+		if attr.value == starlark.None {
+			continue
+		}
+		if err := m.SetField(attr.name, attr.value); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// deepcopy implements deepcopy(metric).
+func deepcopy(_ *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var m *Metric
+	if err := starlark.UnpackPositionalArgs("deepcopy", args, kwargs, 1, &m); err != nil {
+		return nil, err
+	}
+	return &Metric{metric: m.metric.Copy()}, nil
+}
+
+func fromField(v interface{}) starlark.Value {
+	switch v := v.(type) {
+	case float64:
+		return starlark.Float(v)
+	case int64:
+		return starlark.MakeInt64(v)
+	case uint64:
+		return starlark.MakeUint64(v)
+	case string:
+		return starlark.String(v)
+	case bool:
+		return starlark.Bool(v)
+	}
+	return starlark.None
+}
+
+// toField converts a value set by the script.  Ints are written as signed
+// integers, or as unsigned integers if they are too large.
+func toField(v starlark.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case starlark.Float:
+		return float64(v), nil
+	case starlark.Int:
+		if i, ok := v.Int64(); ok {
+			return i, nil
+		}
+		if u, ok := v.Uint64(); ok {
+			return u, nil
+		}
+		return nil, fmt.Errorf("field value %s out of range", v)
+	case starlark.String:
+		return string(v), nil
+	case starlark.Bool:
+		return bool(v), nil
+	}
+	return nil, fmt.Errorf("field value must be an int, float, string or bool, not %s", v.Type())
+}
+
+func toInt64(v starlark.Value) (int64, error) {
+	i, ok := v.(starlark.Int)
+	if !ok {
+		return 0, fmt.Errorf("must be an int, not %s", v.Type())
+	}
+	n, ok := i.Int64()
+	if !ok {
+		return 0, fmt.Errorf("%s out of range", i)
+	}
+	return n, nil
+}
+
+// stringItems returns the items of a dict or mapping with string keys.
+func stringItems(v starlark.Value) ([]starlark.Tuple, error) {
+	m, ok := v.(starlark.IterableMapping)
+	if !ok {
+		return nil, fmt.Errorf("expected a dict, not %s", v.Type())
+	}
+	items := m.Items()
+	for _, item := range items {
+		if _, ok := item[0].(starlark.String); !ok {
+			return nil, fmt.Errorf("keys must be strings, not %s", item[0].Type())
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,188 @@
+package script
+
+import (
+	"fmt"
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/plugins"
+	"github.com/geekflow/straw/plugins/processors"
+	"io/ioutil"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.starlark.net/resolve"
+	"go.starlark.net/starlark"
+)
+
+// defaultMaxSteps is the default limit of execution steps per call.
+const defaultMaxSteps = 100000
+
+var sampleConfig = `
+  ## Source of the script, inline or in a file.  The script defines an
+  ## apply function that is called with each metric and returns None, a
+  ## metric or a list of metrics.
+  source = '''
+def apply(metric):
+    return metric
+'''
+  # script = "/etc/straw/scripts/example.star"
+
+  ## Maximum number of execution steps of the script for a metric, and of
+  ## the top level statements when the processor starts.
+  # max_steps = 100000
+`
+
+// Script runs the metrics through a user script written in Starlark.
+// Scripts have no access to the host and each call is limited to MaxSteps
+// execution steps.
+type Script struct {
+	Source   string `toml:"source"`
+	Script   string `toml:"script"`
+	MaxSteps uint64 `toml:"max_steps"`
+
+	name    string
+	globals starlark.StringDict
+	apply   *starlark.Function
+}
+
+func (*Script) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Script) Description() string {
+	return "Process metrics with a script"
+}
+
+func (s *Script) Init() error {
+	if (s.Source == "") == (s.Script == "") {
+		return fmt.Errorf("script: set one of source or script")
+	}
+
+	src, name := s.Source, "source"
+	if s.Script != "" {
+		b, err := ioutil.ReadFile(s.Script)
+		if err != nil {
+			return fmt.Errorf("script: %v", err)
+		}
+		src, name = string(b), s.Script
+	}
+	s.name = name
+	if s.MaxSteps == 0 {
+		s.MaxSteps = defaultMaxSteps
+	}
+
+	predeclared := starlark.StringDict{
+		"Metric":   starlark.NewBuiltin("Metric", makeMetric),
+		"deepcopy": starlark.NewBuiltin("deepcopy", deepcopy),
+		"state":    starlark.NewDict(0),
+	}
+	_, prog, err := starlark.SourceProgram(name, src, predeclared.Has)
+	if err != nil {
+		return fmt.Errorf("script: %v", err)
+	}
+	// Unlike starlark.ExecFile the globals are not frozen, they keep their
+	// values between calls.
+	globals, err := prog.Init(s.newThread(time.Now()), predeclared)
+	if err != nil {
+		return fmt.Errorf("script: %v", err)
+	}
+	apply, ok := globals["apply"].(*starlark.Function)
+	if !ok {
+		return fmt.Errorf("script: %s: no apply function", name)
+	}
+	if apply.NumParams() != 1 {
+		return fmt.Errorf("script: %s: apply must take one argument", name)
+	}
+
+	s.globals = globals
+	s.apply = apply
+	return nil
+}
+
+// newThread returns a thread for one call of the script, now is the time
+// of the metrics created by the script.
+func (s *Script) newThread(now time.Time) *starlark.Thread {
+	thread := &starlark.Thread{
+		Name: s.name,
+		Print: func(_ *starlark.Thread, msg string) {
+			log.Infof("[processors.script] %s", msg)
+		},
+	}
+	thread.SetMaxExecutionSteps(s.MaxSteps)
+	thread.SetLocal("now", now)
+	return thread
+}
+
+// Apply calls the apply function with each metric.  A metric for which the
+// script fails is dropped.
+func (s *Script) Apply(in ...internal.Metric) []internal.Metric {
+	var out []internal.Metric
+	for _, m := range in {
+		metrics, err := s.call(m)
+		if err != nil {
+			log.Errorf("[processors.script] %s: %v", s.name, err)
+			m.Drop()
+			continue
+		}
+		out = append(out, metrics...)
+	}
+	return out
+}
+
+func (s *Script) call(m internal.Metric) ([]internal.Metric, error) {
+	result, err := starlark.Call(s.newThread(m.Time()), s.apply, starlark.Tuple{&Metric{metric: m}}, nil)
+	if err != nil {
+		if err, ok := err.(*starlark.EvalError); ok {
+			return nil, fmt.Errorf("%s", err.Backtrace())
+		}
+		return nil, err
+	}
+
+	var results []starlark.Value
+	switch r := result.(type) {
+	case starlark.NoneType:
+	case *Metric:
+		results = []starlark.Value{r}
+	case *starlark.List:
+		for i := 0; i < r.Len(); i++ {
+			results = append(results, r.Index(i))
+		}
+	case starlark.Tuple:
+		results = r
+	default:
+		return nil, fmt.Errorf("apply returned %s, not None, a Metric or a list of Metrics", result.Type())
+	}
+
+	// A metric returned more than once is copied, the metric passed in is
+	// dropped if it is not returned.
+	var out []internal.Metric
+	seen := make(map[internal.Metric]bool, len(results))
+	for _, r := range results {
+		rm, ok := r.(*Metric)
+		if !ok {
+			return nil, fmt.Errorf("apply returned a list containing %s, not a Metric", r.Type())
+		}
+		if seen[rm.metric] {
+			out = append(out, rm.metric.Copy())
+			continue
+		}
+		seen[rm.metric] = true
+		out = append(out, rm.metric)
+	}
+	if !seen[m] {
+		m.Drop()
+	}
+	return out, nil
+}
+
+func init() {
+	// Allow the floating point numbers of the metrics.  while loops and
+	// recursion stay disallowed, the execution steps are limited anyway.
+	resolve.AllowFloat = true
+	resolve.AllowLambda = true
+	resolve.AllowNestedDef = true
+	resolve.AllowSet = true
+
+	processors.Add("script", func() plugins.Processor {
+		return &Script{}
+	})
+}
@@ -0,0 +1,199 @@
+package script
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric() internal.Metric {
+	return testutil.MustMetric("mem",
+		map[string]string{"host": "web01"},
+		map[string]interface{}{"used": int64(512), "total": uint64(2048)},
+		time.Unix(10, 0))
+}
+
+func newScript(t *testing.T, source string) *Script {
+	s := &Script{Source: source}
+	require.NoError(t, s.Init())
+	return s
+}
+
+func TestModify(t *testing.T) {
+	s := newScript(t, `
+def apply(metric):
+    metric.name = "memory"
+    metric.tags["env"] = "prod"
+    metric.tags.pop("host")
+    metric.fields["used_percent"] = 100.0 * metric.fields["used"] / metric.fields["total"]
+    metric.fields.pop("total")
+    metric.time += 5 * 1000000000
+    return metric
+`)
+
+	out := s.Apply(newMetric())
+	require.Len(t, out, 1)
+	require.Equal(t, "memory", out[0].Name())
+	require.Equal(t, map[string]string{"env": "prod"}, out[0].Tags())
+	require.Equal(t, map[string]interface{}{"used": int64(512), "used_percent": 25.0}, out[0].Fields())
+	require.Equal(t, time.Unix(15, 0), out[0].Time())
+}
+
+func TestReturnValues(t *testing.T) {
+	s := newScript(t, `
+def apply(metric):
+    kind = metric.tags.get("kind", "")
+    if kind == "drop":
+        return None
+    elif kind == "split":
+        out = []
+        for key, value in sorted(metric.fields.items()):
+            m = Metric(metric.name + "_" + key, tags={"host": "web01"})
+            m.fields["value"] = value
+            out.append(m)
+        return out
+    return [metric, metric]
+`)
+
+	m := newMetric()
+	m.AddTag("kind", "drop")
+	require.Len(t, s.Apply(m), 0)
+
+	m = newMetric()
+	m.AddTag("kind", "split")
+	out := s.Apply(m)
+	require.Len(t, out, 2)
+	require.Equal(t, "mem_total", out[0].Name())
+	require.Equal(t, map[string]interface{}{"value": int64(2048)}, out[0].Fields())
+	require.Equal(t, time.Unix(10, 0), out[0].Time())
+	require.Equal(t, "mem_used", out[1].Name())
+	require.Equal(t, map[string]interface{}{"value": int64(512)}, out[1].Fields())
+
+	// A metric returned twice is copied.
+	out = s.Apply(newMetric())
+	require.Len(t, out, 2)
+	out[0].AddTag("copy", "no")
+	require.False(t, out[1].HasTag("copy"))
+}
+
+func TestState(t *testing.T) {
+	s := newScript(t, `
+seen = []
+
+def apply(metric):
+    count = state.get("count", 0) + 1
+    state["count"] = count
+    seen.append(count)
+    metric.fields["count"] = count
+    metric.fields["seen"] = len(seen)
+    return metric
+`)
+
+	s.Apply(newMetric())
+	out := s.Apply(newMetric())
+	require.Len(t, out, 1)
+	v, _ := out[0].GetField("count")
+	require.Equal(t, int64(2), v)
+	v, _ = out[0].GetField("seen")
+	require.Equal(t, int64(2), v)
+}
+
+func TestErrors(t *testing.T) {
+	s := newScript(t, `
+def apply(metric):
+    if metric.tags["host"] == "web01":
+        return metric.fields["missing"]
+    return metric
+`)
+
+	// The failing metric is dropped, the others pass.
+	other := newMetric()
+	other.AddTag("host", "web02")
+	out := s.Apply(newMetric(), other)
+	require.Equal(t, []internal.Metric{other}, out)
+
+	for _, source := range []string{
+		"",
+		"def apply(metric):\n  return metric\n x = 1\n",
+		"def apply(metric, other):\n  return metric\n",
+		"def apply(metric):\n  while True:\n    pass\n",
+		"x = 1 / 0\ndef apply(metric):\n  return metric\n",
+	} {
+		s := &Script{Source: source}
+		require.Error(t, s.Init(), source)
+	}
+
+}
+
+func TestMapping(t *testing.T) {
+	s := newScript(t, `
+def sum_values(values):
+    total = 0
+    for v in values:
+        total += v
+    return total
+
+def apply(metric):
+    tags = metric.tags
+    fields = metric.fields
+    out = Metric("out", time=0)
+    out.fields["len"] = len(tags) + len(fields)
+    out.fields["has"] = "host" in tags and "missing" not in fields
+    out.tags["keys"] = ",".join(sorted(fields.keys()))
+    out.fields["sum"] = sum_values(fields.values())
+    out.tags["items"] = ",".join([k + "=" + v for k, v in tags.items()])
+    out.tags["default"] = tags.get("missing", "none")
+    out.fields["popped"] = fields.pop("missing", 1.5)
+    out.tags["setdefault"] = tags.setdefault("env", "prod")
+    tags.update({"a": "1"}, b="2")
+    out.tags["after"] = ",".join([k for k in tags])
+    fields.clear()
+    out.fields["cleared"] = len(fields)
+    out.fields["big"] = metric.fields.get("big", 18446744073709551615)
+    return out
+`)
+
+	out := s.Apply(newMetric())
+	require.Len(t, out, 1)
+	require.Equal(t, map[string]string{
+		"keys":       "total,used",
+		"items":      "host=web01",
+		"default":    "none",
+		"setdefault": "prod",
+		"after":      "a,b,env,host",
+	}, out[0].Tags())
+	require.Equal(t, map[string]interface{}{
+		"len":     int64(3),
+		"has":     true,
+		"sum":     int64(2560),
+		"popped":  1.5,
+		"cleared": int64(0),
+		"big":     uint64(18446744073709551615),
+	}, out[0].Fields())
+	require.Equal(t, time.Unix(0, 0), out[0].Time())
+}
+
+func TestLimits(t *testing.T) {
+	for _, source := range []string{
+		// Loops are bounded by the execution steps.
+		"def apply(metric):\n  for i in range(1000000000):\n    pass\n  return metric\n",
+		// Allocations are bounded by the interpreter.
+		"def apply(metric):\n  metric.fields[\"x\"] = \"x\" * 2000000000\n  return metric\n",
+		// Recursion is not allowed.
+		"def f(n):\n  return f(n - 1)\ndef apply(metric):\n  return f(1)\n",
+	} {
+		s := newScript(t, source)
+		require.Len(t, s.Apply(newMetric()), 0, source)
+	}
+
+	// The top level statements are limited as well.
+	s := &Script{Source: "x = [i for i in range(1000000000)]\ndef apply(metric):\n  return metric\n"}
+	require.Error(t, s.Init())
+
+	s = &Script{Source: "def apply(metric):\n  for i in range(100):\n    pass\n  return metric\n", MaxSteps: 10}
+	require.NoError(t, s.Init())
+	require.Len(t, s.Apply(newMetric()), 0)
+}
@@ -0,0 +1,285 @@
+package influx
+
+import (
+	"fmt"
+	"github.com/geekflow/straw/internal"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// FieldType is the type of a field value in line protocol.
+type FieldType int
+
+const (
+	UnknownType FieldType = iota
+	FloatType
+	IntegerType
+	UnsignedType
+	StringType
+	BooleanType
+)
+
+func (t FieldType) String() string {
+	switch t {
+	case FloatType:
+		return "float"
+	case IntegerType:
+		return "integer"
+	case UnsignedType:
+		return "unsigned"
+	case StringType:
+		return "string"
+	case BooleanType:
+		return "boolean"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseFieldType returns the FieldType with the name used by InfluxDB.
+func ParseFieldType(name string) FieldType {
+	switch name {
+	case "float":
+		return FloatType
+	case "integer":
+		return IntegerType
+	case "unsigned":
+		return UnsignedType
+	case "string":
+		return StringType
+	case "boolean":
+		return BooleanType
+	default:
+		return UnknownType
+	}
+}
+
+// FieldConflictMode selects how FieldTypes handles a value whose type differs
+// from the recorded type of the field.
+type FieldConflictMode int
+
+const (
+	// CoerceConflicts converts the value to the recorded type when it can be
+	// done without loss, otherwise the field is dropped.
+	CoerceConflicts FieldConflictMode = iota
+	// DropConflicts always drops the field.
+	DropConflicts
+)
+
+// ParseFieldConflictMode returns the FieldConflictMode with the given name.
+func ParseFieldConflictMode(name string) (FieldConflictMode, error) {
+	switch name {
+	case "coerce":
+		return CoerceConflicts, nil
+	case "drop":
+		return DropConflicts, nil
+	default:
+		return CoerceConflicts, fmt.Errorf("invalid field_type_consistency %q", name)
+	}
+}
+
+// FieldTypes keeps the type of each field of a measurement consistent with
+// the first type observed, or with the type reported by the server.
+type FieldTypes struct {
+	sync.Mutex
+
+	mode             FieldConflictMode
+	fieldTypeSupport FieldTypeSupport
+
+	types map[string]map[string]FieldType // measurement -> field -> type
+}
+
+// NewFieldTypes returns an empty FieldTypes.  The type support must match the
+// serializer so that unsigned values are classified the way they are written.
+func NewFieldTypes(mode FieldConflictMode, typeSupport FieldTypeSupport) *FieldTypes {
+	return &FieldTypes{
+		mode:             mode,
+		fieldTypeSupport: typeSupport,
+		types:            make(map[string]map[string]FieldType),
+	}
+}
+
+// Set records the type of a field, replacing any previously observed type.
+func (t *FieldTypes) Set(measurement, field string, typ FieldType) {
+	t.Lock()
+	defer t.Unlock()
+
+	fields, ok := t.types[measurement]
+	if !ok {
+		fields = make(map[string]FieldType)
+		t.types[measurement] = fields
+	}
+	fields[field] = typ
+}
+
+// Filter applies Apply to each metric and returns the metrics that still
+// have fields.
+func (t *FieldTypes) Filter(metrics []internal.Metric) []internal.Metric {
+	out := make([]internal.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		if m := t.Apply(m); m != nil {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Apply records the type of new fields and converts or removes fields that
+// conflict with the recorded types.  The metric is not modified, a copy is
+// returned if any field changed.  Returns nil if no fields remain.
+func (t *FieldTypes) Apply(m internal.Metric) internal.Metric {
+	t.Lock()
+	defer t.Unlock()
+
+	fields, ok := t.types[m.Name()]
+	if !ok {
+		fields = make(map[string]FieldType)
+		t.types[m.Name()] = fields
+	}
+
+	var out internal.Metric
+	for _, field := range m.FieldList() {
+		typ := t.typeOf(field.Value)
+		want, ok := fields[field.Key]
+		if !ok {
+			fields[field.Key] = typ
+			continue
+		}
+
+		if typ == want {
+			continue
+		}
+
+		if out == nil {
+			out = m.Copy()
+		}
+
+		value, ok := t.convert(field.Value, want)
+		if !ok {
+			out.RemoveField(field.Key)
+			continue
+		}
+		out.AddField(field.Key, value)
+	}
+
+	if out == nil {
+		return m
+	}
+
+	if len(out.FieldList()) == 0 {
+		return nil
+	}
+	return out
+}
+
+// typeOf returns the type the value is serialized as.
+func (t *FieldTypes) typeOf(value interface{}) FieldType {
+	switch value.(type) {
+	case float64:
+		return FloatType
+	case int64:
+		return IntegerType
+	case uint64:
+		if t.fieldTypeSupport&UintSupport == UintSupport {
+			return UnsignedType
+		}
+		return IntegerType
+	case string:
+		return StringType
+	case bool:
+		return BooleanType
+	default:
+		return UnknownType
+	}
+}
+
+// convert returns the value converted to the type, only lossless numeric
+// conversions are made.
+func (t *FieldTypes) convert(value interface{}, typ FieldType) (interface{}, bool) {
+	if t.mode == DropConflicts {
+		return nil, false
+	}
+
+	switch typ {
+	case FloatType:
+		switch v := value.(type) {
+		case int64:
+			return float64(v), true
+		case uint64:
+			return float64(v), true
+		case bool:
+			return boolToInt(v, float64(1), float64(0)), true
+		}
+	case IntegerType:
+		switch v := value.(type) {
+		case uint64:
+			if v <= uint64(MaxInt64) {
+				return int64(v), true
+			}
+		case bool:
+			return boolToInt(v, int64(1), int64(0)), true
+		}
+	case UnsignedType:
+		switch v := value.(type) {
+		case int64:
+			if v >= 0 {
+				return uint64(v), true
+			}
+		case bool:
+			return boolToInt(v, uint64(1), uint64(0)), true
+		}
+	}
+	return nil, false
+}
+
+func boolToInt(v bool, one, zero interface{}) interface{} {
+	if v {
+		return one
+	}
+	return zero
+}
+
+var (
+	fieldTypeConflictRe = regexp.MustCompile(
+		`field type conflict: input field "((?:[^"\\]|\\.)*)" on measurement "((?:[^"\\]|\\.)*)" is type (\w+), already exists as type (\w+)`)
+	droppedRe = regexp.MustCompile(`dropped=(\d+)`)
+)
+
+// FieldTypeConflict describes a field type conflict reported by InfluxDB.
+type FieldTypeConflict struct {
+	Measurement string
+	Field       string
+	Type        FieldType // type of the rejected value
+	Existing    FieldType // type stored by the server
+}
+
+// ParsePartialWrite parses the description of an InfluxDB partial write
+// error.  It returns any field type conflicts and the number of points the
+// server dropped, or -1 if the count is not reported.
+func ParsePartialWrite(desc string) ([]FieldTypeConflict, int) {
+	var conflicts []FieldTypeConflict
+	for _, match := range fieldTypeConflictRe.FindAllStringSubmatch(desc, -1) {
+		conflicts = append(conflicts, FieldTypeConflict{
+			Field:       unquote(match[1]),
+			Measurement: unquote(match[2]),
+			Type:        ParseFieldType(match[3]),
+			Existing:    ParseFieldType(match[4]),
+		})
+	}
+
+	dropped := -1
+	if match := droppedRe.FindStringSubmatch(desc); match != nil {
+		if n, err := strconv.Atoi(match[1]); err == nil {
+			dropped = n
+		}
+	}
+	return conflicts, dropped
+}
+
+func unquote(s string) string {
+	if v, err := strconv.Unquote(`"` + s + `"`); err == nil {
+		return v
+	}
+	return s
+}
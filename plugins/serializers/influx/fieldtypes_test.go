@@ -0,0 +1,77 @@
+package influx
+
+import (
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/metric"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func fieldMetric(fields map[string]interface{}) internal.Metric {
+	return MustMetric(metric.New("cpu", map[string]string{}, fields, time.Unix(0, 0)))
+}
+
+func TestFieldTypesCoerce(t *testing.T) {
+	types := NewFieldTypes(CoerceConflicts, 0)
+
+	m := fieldMetric(map[string]interface{}{"value": 42.0, "up": int64(1), "state": "ok"})
+	require.Equal(t, m, types.Apply(m))
+
+	m = fieldMetric(map[string]interface{}{"value": int64(43), "up": true, "state": int64(2)})
+	out := types.Apply(m)
+	require.Equal(t, map[string]interface{}{"value": 43.0, "up": int64(1)}, out.Fields())
+
+	// The original metric is not modified.
+	require.Equal(t, map[string]interface{}{"value": int64(43), "up": true, "state": int64(2)}, m.Fields())
+}
+
+func TestFieldTypesDrop(t *testing.T) {
+	types := NewFieldTypes(DropConflicts, 0)
+
+	types.Apply(fieldMetric(map[string]interface{}{"value": 42.0}))
+	require.Nil(t, types.Apply(fieldMetric(map[string]interface{}{"value": int64(43)})))
+
+	out := types.Apply(fieldMetric(map[string]interface{}{"value": int64(43), "idle": 1.0}))
+	require.Equal(t, map[string]interface{}{"idle": 1.0}, out.Fields())
+}
+
+func TestFieldTypesUnsigned(t *testing.T) {
+	types := NewFieldTypes(CoerceConflicts, 0)
+
+	// Without uint support unsigned values are written as integers.
+	types.Apply(fieldMetric(map[string]interface{}{"value": int64(1)}))
+	m := fieldMetric(map[string]interface{}{"value": uint64(2)})
+	require.Equal(t, m, types.Apply(m))
+
+	types = NewFieldTypes(CoerceConflicts, UintSupport)
+	types.Apply(fieldMetric(map[string]interface{}{"value": uint64(1)}))
+	out := types.Apply(fieldMetric(map[string]interface{}{"value": int64(2)}))
+	require.Equal(t, map[string]interface{}{"value": uint64(2)}, out.Fields())
+	require.Nil(t, types.Apply(fieldMetric(map[string]interface{}{"value": int64(-2)})))
+}
+
+func TestFieldTypesSet(t *testing.T) {
+	types := NewFieldTypes(CoerceConflicts, 0)
+	types.Apply(fieldMetric(map[string]interface{}{"value": int64(1)}))
+	types.Set("cpu", "value", FloatType)
+
+	out := types.Apply(fieldMetric(map[string]interface{}{"value": int64(2)}))
+	require.Equal(t, map[string]interface{}{"value": 2.0}, out.Fields())
+}
+
+func TestParsePartialWrite(t *testing.T) {
+	desc := `partial write: field type conflict: input field "value" on measurement "cpu" ` +
+		`is type integer, already exists as type float dropped=3`
+
+	conflicts, dropped := ParsePartialWrite(desc)
+	require.Equal(t, []FieldTypeConflict{
+		{Measurement: "cpu", Field: "value", Type: IntegerType, Existing: FloatType},
+	}, conflicts)
+	require.Equal(t, 3, dropped)
+
+	conflicts, dropped = ParsePartialWrite("partial write: points beyond retention policy")
+	require.Empty(t, conflicts)
+	require.Equal(t, -1, dropped)
+}
@@ -65,6 +65,7 @@ type Serializer struct {
 	header []byte
 	footer []byte
 	pair   []byte
+	fields []*internal.Field
 }
 
 func NewSerializer() *Serializer {
@@ -206,15 +207,20 @@ func (s *Serializer) writeMetric(w io.Writer, m internal.Metric) error {
 
 	s.buildFooter(m)
 
+	fields := m.FieldList()
 	if s.fieldSortOrder == SortFields {
-		sort.Slice(m.FieldList(), func(i, j int) bool {
-			return m.FieldList()[i].Key < m.FieldList()[j].Key
+		// The field list may be shared with copies of the metric, so sort a
+		// reusable copy of it.
+		s.fields = append(s.fields[:0], fields...)
+		sort.Slice(s.fields, func(i, j int) bool {
+			return s.fields[i].Key < s.fields[j].Key
 		})
+		fields = s.fields
 	}
 
 	pairsLen := 0
 	firstField := true
-	for _, field := range m.FieldList() {
+	for _, field := range fields {
 		err = s.buildFieldPair(field.Key, field.Value)
 		if err != nil {
 			log.Printf(
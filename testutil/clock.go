@@ -0,0 +1,89 @@
+package testutil
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a fake internal.Clock whose time only changes when it is set or
+// advanced by the test.
+type Clock struct {
+	sync.Mutex
+	cond *sync.Cond
+
+	now     time.Time
+	waiters []*clockWaiter
+}
+
+type clockWaiter struct {
+	until time.Time
+	c     chan time.Time
+}
+
+// NewClock returns a Clock set to now.
+func NewClock(now time.Time) *Clock {
+	c := &Clock{now: now}
+	c.cond = sync.NewCond(&c.Mutex)
+	return c
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the time once the clock has been
+// advanced by the duration.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.Lock()
+	defer c.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+
+	c.waiters = append(c.waiters, &clockWaiter{until: c.now.Add(d), c: ch})
+	c.cond.Broadcast()
+	return ch
+}
+
+// Add advances the clock by the duration, waking the waiters that are due.
+func (c *Clock) Add(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.set(c.now.Add(d))
+}
+
+// Set sets the clock to the time, waking the waiters that are due.
+func (c *Clock) Set(t time.Time) {
+	c.Lock()
+	defer c.Unlock()
+	c.set(t)
+}
+
+func (c *Clock) set(t time.Time) {
+	c.now = t
+
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.until.After(t) {
+			waiters = append(waiters, w)
+			continue
+		}
+		w.c <- t
+	}
+	c.waiters = waiters
+}
+
+// BlockUntil waits until at least n callers are waiting on the clock.
+func (c *Clock) BlockUntil(n int) {
+	c.Lock()
+	defer c.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
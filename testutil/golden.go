@@ -0,0 +1,51 @@
+package testutil
+
+import (
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/plugins/serializers/influx"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// RequireGolden compares the metrics, as line protocol with sorted lines and
+// fields, to the contents of a golden file.  When the tests are run with
+// UPDATE_GOLDEN=1 in the environment the golden file is written instead.
+func RequireGolden(t *testing.T, path string, metrics []internal.Metric) {
+	t.Helper()
+
+	actual := FormatLineProtocol(t, metrics)
+	if os.Getenv("UPDATE_GOLDEN") == "1" {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(actual), 0644))
+		return
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	require.NoError(t, err, "run the test with UPDATE_GOLDEN=1 to create the golden file")
+	require.Equal(t, string(expected), actual, "run the test with UPDATE_GOLDEN=1 to accept the changes")
+}
+
+// FormatLineProtocol returns the metrics as line protocol with the lines and
+// fields sorted.
+func FormatLineProtocol(t *testing.T, metrics []internal.Metric) string {
+	t.Helper()
+
+	serializer := influx.NewSerializer()
+	serializer.SetFieldSortOrder(influx.SortFields)
+	serializer.SetFieldTypeSupport(influx.UintSupport)
+
+	lines := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		buf, err := serializer.Serialize(m)
+		require.NoError(t, err)
+		lines = append(lines, string(buf))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "")
+}
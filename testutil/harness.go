@@ -0,0 +1,48 @@
+package testutil
+
+import (
+	"github.com/geekflow/straw/internal"
+	"github.com/geekflow/straw/plugins"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// HarnessTime is the initial time of the clock of an InputHarness.
+var HarnessTime = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// InputHarness gathers an input plugin with a fake clock, so that metrics
+// without an explicit timestamp are reproducible.
+type InputHarness struct {
+	Input plugins.Input
+	Clock *Clock
+	Acc   *Accumulator
+}
+
+// NewInputHarness returns an InputHarness with its clock set to HarnessTime.
+func NewInputHarness(input plugins.Input) *InputHarness {
+	clock := NewClock(HarnessTime)
+	return &InputHarness{
+		Input: input,
+		Clock: clock,
+		Acc:   &Accumulator{TimeFunc: clock.Now},
+	}
+}
+
+// Gather runs the input once and returns the metrics of this gather.
+func (h *InputHarness) Gather(t *testing.T) []internal.Metric {
+	t.Helper()
+
+	h.Acc.ClearMetrics()
+	require.NoError(t, h.Input.Gather(h.Acc))
+	require.NoError(t, h.Acc.FirstError())
+	return h.Acc.GetTelegrafMetrics()
+}
+
+// RequireGolden gathers the input once and compares the metrics with the
+// golden file, see RequireGolden.
+func (h *InputHarness) RequireGolden(t *testing.T, path string) {
+	t.Helper()
+	RequireGolden(t, path, h.Gather(t))
+}